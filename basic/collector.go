@@ -1,16 +1,20 @@
 package basic
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"sync"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/percona/rds_exporter/config"
 	"github.com/percona/rds_exporter/sessions"
+	"github.com/percona/rds_exporter/status"
 )
 
 //go:generate go run generate/main.go generate/utils.go
@@ -24,27 +28,206 @@ var (
 	)
 )
 
+// Metric describes a single CloudWatch metric. Metrics are generated from catalog.yml.
 type Metric struct {
 	cwName         string
 	prometheusName string
 	prometheusHelp string
+	unit           string   // CloudWatch unit
+	engines        []string // engines that publish this metric
+	dimensions     []string // CloudWatch dimensions this metric is published with
+}
+
+// engineFamily returns engine name used in catalog.yml for given RDS engine name,
+// for example, "oracle" for "oracle-ee" and "custom-oracle-ee".
+func engineFamily(engine string) string {
+	engine = strings.TrimPrefix(engine, "custom-")
+	switch {
+	case engine == "aurora": // Aurora MySQL 1.x
+		return "aurora-mysql"
+	case strings.HasPrefix(engine, "oracle-"):
+		return "oracle"
+	case strings.HasPrefix(engine, "sqlserver-"):
+		return "sqlserver"
+	default:
+		return engine
+	}
+}
+
+// publishedBy returns true if metric is published for instances with given RDS engine.
+// It returns true for unknown (empty) engine.
+func (m Metric) publishedBy(engine string) bool {
+	if engine == "" {
+		return true
+	}
+	engine = engineFamily(engine)
+	for _, e := range m.engines {
+		if e == engine {
+			return true
+		}
+	}
+	return false
 }
 
 type Collector struct {
-	config   *config.Config
-	sessions *sessions.Sessions
-	metrics  []Metric
-	l        log.Logger
+	config    *config.Config
+	sessions  *sessions.Sessions
+	metrics   []Metric
+	status    *status.Tracker
+	fleet     scraper    // nil if fleet metrics are disabled
+	discovery *discovery // nil if metrics discovery is disabled
+	l         log.Logger
+
+	// background polling mode: latest results of every poller
+	polling bool
+	rw      sync.RWMutex
+	cached  []scrapeResult
+
+	// on-demand mode: scrape shared by concurrent Collect calls
+	m        sync.Mutex
+	inflight *inflightScrape
+}
+
+// scraper is a common interface of Scraper and FleetScraper.
+type scraper interface {
+	// scrape returns metrics and the latest datapoint timestamps.
+	scrape(ctx context.Context) ([]prometheus.Metric, datapointAges)
+}
+
+// scrapeResult contains metrics and the latest datapoint timestamps of a single scrape.
+// Datapoint ages are calculated from timestamps when metrics are collected.
+type scrapeResult struct {
+	metrics []prometheus.Metric
+	ages    datapointAges
+}
+
+// inflightScrape represents a scrape shared by concurrent Collect calls.
+type inflightScrape struct {
+	done chan struct{}
+	res  scrapeResult
 }
 
 // New creates a new instance of a Collector.
-func New(config *config.Config, sessions *sessions.Sessions, logger log.Logger) *Collector {
-	return &Collector{
+// If polling interval is configured, it also starts background scraping until ctx is canceled.
+func New(ctx context.Context, config *config.Config, sessions *sessions.Sessions, logger log.Logger) *Collector {
+	c := &Collector{
 		config:   config,
 		sessions: sessions,
 		metrics:  Metrics,
+		status:   status.New("basic"),
 		l:        log.With(logger, "component", "basic"),
 	}
+
+	if config.Basic.Discover {
+		c.discovery = newDiscovery(config.Basic.DiscoverInterval)
+	}
+
+	if config.Basic.Fleet {
+		c.fleet = NewFleetScraper(c)
+	}
+
+	if interval := config.Basic.Interval; interval > 0 {
+		level.Info(c.l).Log("msg", fmt.Sprintf("Updating basic metrics every %s.", interval))
+		c.startPolling(ctx, interval)
+	}
+
+	return c
+}
+
+// startPolling starts a separate poller for every session and fleet metrics,
+// so a slow or throttled region does not delay others.
+func (e *Collector) startPolling(ctx context.Context, interval time.Duration) {
+	var pollers []func() scraper
+	for _, group := range e.instancesBySession() {
+		sess := group.session
+		pollers = append(pollers, func() scraper { return NewScraper(sess, e.sessionInstances(sess), e) })
+	}
+	if e.fleet != nil {
+		pollers = append(pollers, func() scraper { return e.fleet })
+	}
+
+	e.startPollers(ctx, interval, pollers)
+}
+
+// startPollers performs the first scrape of every poller synchronously so collector has all metrics,
+// and then starts polling in background until ctx is canceled.
+// newScraper functions are called on every iteration, so changes of instances are picked up.
+func (e *Collector) startPollers(ctx context.Context, interval time.Duration, pollers []func() scraper) {
+	e.polling = true
+	e.cached = make([]scrapeResult, len(pollers))
+
+	var wg sync.WaitGroup
+	for i, newScraper := range pollers {
+		i, newScraper := i, newScraper
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			scrapeCtx, cancel := context.WithTimeout(ctx, interval)
+			e.setResult(i, scrapeWith(scrapeCtx, newScraper()))
+			cancel()
+		}()
+	}
+	wg.Wait()
+
+	for i, newScraper := range pollers {
+		go e.poll(ctx, interval, i, newScraper)
+	}
+}
+
+// poll scrapes metrics in loop and caches them as i-th poller's result until context is canceled.
+func (e *Collector) poll(ctx context.Context, interval time.Duration, i int, newScraper func() scraper) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			// nothing
+		case <-ctx.Done():
+			return
+		}
+
+		scrapeCtx, cancel := context.WithTimeout(ctx, interval)
+		res := scrapeWith(scrapeCtx, newScraper())
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		e.setResult(i, res)
+	}
+}
+
+// setResult saves latest result of i-th poller.
+func (e *Collector) setResult(i int, res scrapeResult) {
+	e.rw.Lock()
+	e.cached[i] = res
+	e.rw.Unlock()
+}
+
+// scrapeWith scrapes metrics with given scraper.
+func scrapeWith(ctx context.Context, s scraper) scrapeResult {
+	metrics, ages := s.scrape(ctx)
+	return scrapeResult{metrics: metrics, ages: ages}
+}
+
+// engineMetrics returns collector's metrics published for instances with given RDS engine.
+func (e *Collector) engineMetrics(engine string) []Metric {
+	res := make([]Metric, 0, len(e.metrics))
+	for _, m := range e.metrics {
+		if m.publishedBy(engine) {
+			res = append(res, m)
+		}
+	}
+	return res
+}
+
+// statistics returns configured or default statistics for given metric.
+func (e *Collector) statistics(metric Metric) []string {
+	if statistics := e.config.Basic.Statistics[metric.cwName]; len(statistics) != 0 {
+		return statistics
+	}
+	return defaultStatistics
 }
 
 func (e *Collector) Describe(ch chan<- *prometheus.Desc) {
@@ -54,36 +237,133 @@ func (e *Collector) Describe(ch chan<- *prometheus.Desc) {
 func (e *Collector) Collect(ch chan<- prometheus.Metric) {
 	now := time.Now()
 	e.collect(ch)
+	e.status.Collect(ch)
 
 	// Collect scrape time
 	ch <- prometheus.MustNewConstMetric(scrapeTimeDesc, prometheus.GaugeValue, time.Since(now).Seconds())
 }
 
 func (e *Collector) collect(ch chan<- prometheus.Metric) {
-	var wg sync.WaitGroup
-	defer wg.Wait()
+	var results []scrapeResult
+	if e.polling {
+		e.rw.RLock()
+		results = append(results, e.cached...)
+		e.rw.RUnlock()
+	} else {
+		results = append(results, e.scrapeShared())
+	}
 
-	for _, instance := range e.config.Instances {
-		if instance.DisableBasicMetrics {
-			level.Debug(e.l).Log("msg", fmt.Sprintf("Instance %s has disabled basic metrics, skipping.", instance))
-			continue
+	// datapoint ages are calculated now, so they are not frozen between polls
+	now := time.Now()
+	for _, res := range results {
+		for _, m := range res.metrics {
+			ch <- m
 		}
-		instance := instance
+		if e.config.Basic.DatapointAge {
+			for _, m := range res.ages.metrics(now) {
+				ch <- m
+			}
+		}
+	}
+}
+
+// scrapeShared scrapes all sessions, or waits for the scrape started by concurrent call and returns its results.
+func (e *Collector) scrapeShared() scrapeResult {
+	e.m.Lock()
+	if f := e.inflight; f != nil {
+		e.m.Unlock()
+		<-f.done
+		return f.res
+	}
+	f := &inflightScrape{
+		done: make(chan struct{}),
+	}
+	e.inflight = f
+	e.m.Unlock()
+
+	f.res = e.scrape(context.Background())
+
+	e.m.Lock()
+	e.inflight = nil
+	e.m.Unlock()
+	close(f.done)
+	return f.res
+}
+
+// scrapers returns scrapers for all sessions, and fleet scraper if enabled.
+func (e *Collector) scrapers() []scraper {
+	var res []scraper
+	for _, group := range e.instancesBySession() {
+		res = append(res, NewScraper(group.session, group.instances, e))
+	}
+	if e.fleet != nil {
+		res = append(res, e.fleet)
+	}
+	return res
+}
+
+// scrape scrapes all sessions concurrently.
+func (e *Collector) scrape(ctx context.Context) scrapeResult {
+	var wg sync.WaitGroup
+	var m sync.Mutex
+	res := scrapeResult{ages: make(datapointAges)}
+	for _, s := range e.scrapers() {
+		s := s
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 
-			s := NewScraper(&instance, e, ch)
-			if s == nil {
-				level.Error(e.l).Log("msg", fmt.Sprintf("No scraper for %s, skipping.", instance))
-				return
-			}
-			s.Scrape()
+			metrics, ages := s.scrape(ctx)
+			m.Lock()
+			res.metrics = append(res.metrics, metrics...)
+			res.ages.merge(ages)
+			m.Unlock()
 		}()
 	}
+	wg.Wait()
+
+	return res
+}
+
+// sessionInstances contains instances sharing a single session.
+type sessionInstances struct {
+	session   *session.Session
+	instances []*sessions.Instance
+}
+
+// instancesBySession returns instances with enabled basic metrics grouped by sessions to batch requests.
+func (e *Collector) instancesBySession() []sessionInstances {
+	var res []sessionInstances
+	for sess, instances := range e.sessions.AllSessions() {
+		group := sessionInstances{session: sess}
+		for i, instance := range instances {
+			if instance.DisableBasicMetrics {
+				level.Debug(e.l).Log("msg", fmt.Sprintf("Instance %s has disabled basic metrics, skipping.", instance))
+				continue
+			}
+			group.instances = append(group.instances, &instances[i])
+		}
+		if len(group.instances) > 0 {
+			res = append(res, group)
+		}
+	}
+
+	return res
+}
+
+// sessionInstances returns instances with enabled basic metrics of given session.
+func (e *Collector) sessionInstances(sess *session.Session) []*sessions.Instance {
+	for _, group := range e.instancesBySession() {
+		if group.session == sess {
+			return group.instances
+		}
+	}
+	return nil
 }
 
 // check interfaces
 var (
 	_ prometheus.Collector = (*Collector)(nil)
+	_ scraper              = (*Scraper)(nil)
+	_ scraper              = (*FleetScraper)(nil)
 )
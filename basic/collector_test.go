@@ -1,17 +1,21 @@
 package basic
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/percona/exporter_shared/helpers"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/promlog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/percona/rds_exporter/client"
+	"github.com/percona/rds_exporter/client/clienttest"
 	"github.com/percona/rds_exporter/config"
 	"github.com/percona/rds_exporter/sessions"
 )
@@ -20,11 +24,10 @@ func TestCollector(t *testing.T) {
 	cfg, err := config.Load("../config.tests.yml")
 	require.NoError(t, err)
 	logger := promlog.New(&promlog.Config{})
-	client := client.New(logger)
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, false)
+	sess, err := sessions.New(cfg.Instances, clienttest.New(t, logger).HTTP(), logger, false)
 	require.NoError(t, err)
 
-	c := New(cfg, sess, logger)
+	c := New(context.Background(), cfg, sess, logger)
 
 	actualMetrics := helpers.ReadMetrics(helpers.CollectMetrics(c))
 	sort.Slice(actualMetrics, func(i, j int) bool { return actualMetrics[i].Less(actualMetrics[j]) })
@@ -55,7 +58,7 @@ func TestCollectorDisableBasicMetrics(t *testing.T) {
 	cfg, err := config.Load("../config.tests.yml")
 	require.NoError(t, err)
 	logger := promlog.New(&promlog.Config{})
-	client := client.New(logger)
+	client := clienttest.New(t, logger)
 	instanceGroups := make(map[bool][]string, 2)
 	for i := range cfg.Instances {
 		// Disable basic metrics in even instances.
@@ -68,7 +71,7 @@ func TestCollectorDisableBasicMetrics(t *testing.T) {
 	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, false)
 	require.NoError(t, err)
 
-	c := New(cfg, sess, logger)
+	c := New(context.Background(), cfg, sess, logger)
 
 	actualMetrics := helpers.ReadMetrics(helpers.CollectMetrics(c))
 	actualLines := helpers.Format(helpers.WriteMetrics(actualMetrics))
@@ -93,3 +96,171 @@ func TestCollectorDisableBasicMetrics(t *testing.T) {
 		assert.Truef(t, hasMetricForInstance(actualLines, inst), "Did not find metrics for enabled instance %s", inst)
 	}
 }
+
+func TestMetricPublishedBy(t *testing.T) {
+	commitLatency := Metric{cwName: "CommitLatency", engines: []string{"aurora-mysql", "aurora-postgresql"}}
+	assert.True(t, commitLatency.publishedBy("aurora-postgresql"))
+	assert.True(t, commitLatency.publishedBy("aurora"))
+	assert.True(t, commitLatency.publishedBy(""))
+	assert.False(t, commitLatency.publishedBy("mysql"))
+
+	c := newTestCollector(&config.Config{}, Metrics)
+	for _, engine := range []string{"mysql", "mariadb", "postgres", "oracle-ee", "custom-sqlserver-ee", "aurora-mysql", "aurora-postgresql"} {
+		metrics := c.engineMetrics(engine)
+		assert.NotEmpty(t, metrics, "%s", engine)
+		assert.Less(t, len(metrics), len(Metrics), "%s", engine)
+	}
+	assert.Equal(t, Metrics, c.engineMetrics(""))
+}
+
+// fakeScraper returns a single metric with the number of scrape calls as a value, and given datapoint timestamps.
+type fakeScraper struct {
+	name    string        // metric name, fake_scrapes if empty
+	delay   time.Duration // scrape duration
+	ages    datapointAges
+	m       sync.Mutex
+	calls   int
+	started chan struct{} // receives on every scrape call, if not nil
+	release chan struct{} // scrape waits for it, if not nil
+}
+
+func (f *fakeScraper) scrape(ctx context.Context) ([]prometheus.Metric, datapointAges) {
+	f.m.Lock()
+	f.calls++
+	calls := f.calls
+	f.m.Unlock()
+
+	if f.started != nil {
+		f.started <- struct{}{}
+	}
+	if f.release != nil {
+		<-f.release
+	}
+	time.Sleep(f.delay)
+
+	name := f.name
+	if name == "" {
+		name = "fake_scrapes"
+	}
+	desc := prometheus.NewDesc(name, "Number of scrapes.", nil, nil)
+	return []prometheus.Metric{prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, float64(calls))}, f.ages
+}
+
+func (f *fakeScraper) Calls() int {
+	f.m.Lock()
+	defer f.m.Unlock()
+	return f.calls
+}
+
+func TestCollectorScrapeShared(t *testing.T) {
+	const n = 10
+	c := newTestCollector(&config.Config{}, nil)
+	s := &fakeScraper{
+		started: make(chan struct{}, n),
+		release: make(chan struct{}),
+	}
+	c.fleet = s
+
+	var wg sync.WaitGroup
+	results := make([]scrapeResult, n)
+	collect := func(i int) {
+		defer wg.Done()
+		results[i] = c.scrapeShared()
+	}
+
+	wg.Add(1)
+	go collect(0)
+	<-s.started
+
+	// other calls should wait for the scrape in flight instead of starting their own
+	wg.Add(n - 1)
+	for i := 1; i < n; i++ {
+		go collect(i)
+	}
+	time.Sleep(100 * time.Millisecond)
+	close(s.release)
+	wg.Wait()
+
+	assert.Equal(t, 1, s.Calls())
+	for i := 1; i < n; i++ {
+		assert.Equal(t, results[0], results[i])
+	}
+
+	// next call starts a new scrape
+	c.scrapeShared()
+	assert.Equal(t, 2, s.Calls())
+}
+
+func TestCollectorPolling(t *testing.T) {
+	c := newTestCollector(&config.Config{}, nil)
+	s := new(fakeScraper)
+	c.fleet = s
+
+	ctx, cancel := context.WithCancel(context.Background())
+	c.startPolling(ctx, 10*time.Millisecond)
+
+	// the first scrape is synchronous, Collect returns cached metrics without scraping
+	assert.Equal(t, 1, s.Calls())
+	metrics := helpers.ReadMetrics(helpers.CollectMetrics(c))
+	assert.Equal(t, 1.0, readMetrics(metrics, "fake_scrapes")["fake_scrapes/"])
+
+	assert.Eventually(t, func() bool {
+		metrics := helpers.ReadMetrics(helpers.CollectMetrics(c))
+		return readMetrics(metrics, "fake_scrapes")["fake_scrapes/"] >= 3
+	}, time.Second, 5*time.Millisecond)
+
+	// polling stops when context is canceled
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	calls := s.Calls()
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, calls, s.Calls())
+}
+
+func TestCollectorPollers(t *testing.T) {
+	c := newTestCollector(&config.Config{}, nil)
+	slow := &fakeScraper{name: "slow_scrapes", delay: 500 * time.Millisecond}
+	fast := &fakeScraper{name: "fast_scrapes"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.startPollers(ctx, 10*time.Millisecond, []func() scraper{
+		func() scraper { return slow },
+		func() scraper { return fast },
+	})
+
+	// slow session does not delay polling of other sessions
+	assert.Eventually(t, func() bool {
+		metrics := helpers.ReadMetrics(helpers.CollectMetrics(c))
+		return readMetrics(metrics, "fast_scrapes")["fast_scrapes/"] >= 5
+	}, 300*time.Millisecond, 5*time.Millisecond)
+	metrics := helpers.ReadMetrics(helpers.CollectMetrics(c))
+	assert.Equal(t, 1.0, readMetrics(metrics, "slow_scrapes")["slow_scrapes/"])
+}
+
+func TestCollectorDatapointAge(t *testing.T) {
+	ts := time.Now().Add(-time.Minute)
+	ages := make(datapointAges)
+	ages.add(&query{
+		instance:    &sessions.Instance{Region: "us-east-1", Instance: "rds-a"},
+		constLabels: prometheus.Labels{"region": "us-east-1", "instance": "rds-a"},
+		metric:      Metric{cwName: "CPUUtilization"},
+	}, ts)
+	s := &fakeScraper{ages: ages}
+
+	c := newTestCollector(&config.Config{Basic: config.Basic{DatapointAge: true}}, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	c.startPollers(ctx, time.Hour, []func() scraper{func() scraper { return s }})
+
+	// age is calculated on every collect, not frozen at poll time
+	age := func() float64 {
+		metrics := helpers.ReadMetrics(helpers.CollectMetrics(c))
+		return readMetrics(metrics, datapointAgeName)["aws_rds_datapoint_age_seconds/rds-a/CPUUtilization"]
+	}
+	first := age()
+	assert.InDelta(t, 60, first, 5)
+	time.Sleep(50 * time.Millisecond)
+	assert.Greater(t, age(), first)
+	assert.Equal(t, 1, s.Calls())
+}
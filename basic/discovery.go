@@ -0,0 +1,194 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/go-kit/log/level"
+
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// maxDiscoveries is the maximal number of concurrent ListMetrics requests of a single scrape.
+const maxDiscoveries = 10
+
+// DiscoverInterval is the default interval between metrics discoveries for a single instance.
+// It is used when not set in the configuration.
+var DiscoverInterval = time.Hour
+
+// discovery contains metrics discovered with ListMetrics for every instance.
+type discovery struct {
+	interval time.Duration
+
+	rw        sync.Mutex
+	instances map[string]*discovered // instance.String() -> discovered metrics
+}
+
+// discovered contains metrics discovered for a single instance.
+type discovered struct {
+	metrics []Metric
+	updated time.Time
+}
+
+func newDiscovery(interval time.Duration) *discovery {
+	if interval == 0 {
+		interval = DiscoverInterval
+	}
+	return &discovery{
+		interval:  interval,
+		instances: make(map[string]*discovered),
+	}
+}
+
+// get returns discovered metrics for given instance (nil if never discovered),
+// and true if they were discovered recently.
+func (d *discovery) get(instance *sessions.Instance, now time.Time) ([]Metric, bool) {
+	d.rw.Lock()
+	defer d.rw.Unlock()
+
+	i := d.instances[instance.String()]
+	if i == nil {
+		return nil, false
+	}
+	return i.metrics, now.Sub(i.updated) < d.interval
+}
+
+// set saves discovered metrics for given instance.
+func (d *discovery) set(instance *sessions.Instance, metrics []Metric, now time.Time) {
+	d.rw.Lock()
+	d.instances[instance.String()] = &discovered{
+		metrics: metrics,
+		updated: now,
+	}
+	d.rw.Unlock()
+}
+
+// unknownMetric returns metric with automatically generated name and help for CloudWatch metric absent in the catalog.
+func unknownMetric(cwName string) Metric {
+	return Metric{
+		cwName:         cwName,
+		prometheusName: "aws_rds_" + snakeCase(cwName) + "_average",
+		prometheusHelp: cwName + " CloudWatch metric, discovered with ListMetrics.",
+	}
+}
+
+// mergeMetrics returns known metrics with given CloudWatch names in the known order,
+// followed by unknown metrics sorted by name.
+func mergeMetrics(known []Metric, cwNames []string) []Metric {
+	names := make(map[string]struct{}, len(cwNames))
+	for _, n := range cwNames {
+		names[n] = struct{}{}
+	}
+
+	res := make([]Metric, 0, len(names))
+	for _, m := range known {
+		if _, ok := names[m.cwName]; ok {
+			res = append(res, m)
+			delete(names, m.cwName)
+		}
+	}
+
+	unknown := make([]string, 0, len(names))
+	for n := range names {
+		unknown = append(unknown, n)
+	}
+	sort.Strings(unknown)
+	for _, n := range unknown {
+		res = append(res, unknownMetric(n))
+	}
+	return res
+}
+
+// discover returns metrics CloudWatch has for given instance.
+func (s *Scraper) discover(ctx context.Context, instance *sessions.Instance) ([]Metric, error) {
+	input := &cloudwatch.ListMetricsInput{
+		Namespace: aws.String("AWS/RDS"),
+		Dimensions: []*cloudwatch.DimensionFilter{{
+			Name:  aws.String("DBInstanceIdentifier"),
+			Value: aws.String(instance.Instance),
+		}},
+		RecentlyActive: aws.String(cloudwatch.RecentlyActivePt3h),
+	}
+	var names []string
+	err := s.svc.ListMetricsPagesWithContext(ctx, input, func(page *cloudwatch.ListMetricsOutput, lastPage bool) bool {
+		for _, m := range page.Metrics {
+			// skip metrics with additional dimensions
+			if len(m.Dimensions) == 1 {
+				names = append(names, aws.StringValue(m.MetricName))
+			}
+		}
+
+		return true // continue pagination
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeMetrics(s.collector.metrics, names), nil
+}
+
+// instanceMetrics returns metrics that should be scraped for given instance:
+// all known metrics if discovery is disabled, or discovered metrics otherwise.
+func (s *Scraper) instanceMetrics(ctx context.Context, instance *sessions.Instance) []Metric {
+	d := s.collector.discovery
+	if d == nil {
+		return s.collector.engineMetrics(instance.Engine)
+	}
+
+	now := time.Now()
+	metrics, fresh := d.get(instance, now)
+	if fresh {
+		return metrics
+	}
+
+	discovered, err := s.discover(ctx, instance)
+	if err != nil {
+		level.Error(s.collector.l).Log("msg", fmt.Sprintf("Failed to discover metrics for %s.", instance), "error", err)
+		if metrics == nil {
+			return s.collector.engineMetrics(instance.Engine)
+		}
+		return metrics
+	}
+
+	d.set(instance, discovered, now)
+	return discovered
+}
+
+// allInstanceMetrics returns metrics that should be scraped for every instance.
+// Metrics of several instances are discovered concurrently.
+func (s *Scraper) allInstanceMetrics(ctx context.Context) map[*sessions.Instance][]Metric {
+	res := make(map[*sessions.Instance][]Metric, len(s.instances))
+	if s.collector.discovery == nil {
+		for _, instance := range s.instances {
+			res[instance] = s.collector.engineMetrics(instance.Engine)
+		}
+		return res
+	}
+
+	var wg sync.WaitGroup
+	var m sync.Mutex
+	sem := make(chan struct{}, maxDiscoveries)
+	for _, instance := range s.instances {
+		instance := instance
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+
+			metrics := s.instanceMetrics(ctx, instance)
+			m.Lock()
+			res[instance] = metrics
+			m.Unlock()
+		}()
+	}
+	wg.Wait()
+	return res
+}
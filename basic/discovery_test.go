@@ -0,0 +1,61 @@
+package basic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona/rds_exporter/sessions"
+)
+
+func TestUnknownMetric(t *testing.T) {
+	for cwName, name := range map[string]string{
+		"DBLoad":                      "aws_rds_db_load_average",
+		"ACUUtilization":              "aws_rds_acu_utilization_average",
+		"ServerlessDatabaseCapacity":  "aws_rds_serverless_database_capacity_average",
+		"EBSIOBalance%":               "aws_rds_ebsio_balance_percent_average",
+		"Replica-Lag.Total/Sec Count": "aws_rds_replica_lag_total_sec_count_average",
+		"Lagé":                        "aws_rds_lag__average",
+	} {
+		assert.Equal(t, name, unknownMetric(cwName).prometheusName)
+		assert.True(t, model.IsValidMetricName(model.LabelValue(name)), "%s", name)
+	}
+}
+
+func TestMergeMetrics(t *testing.T) {
+	known := []Metric{
+		{cwName: "CPUUtilization", prometheusName: "node_cpu_average"},
+		{cwName: "DiskQueueDepth", prometheusName: "aws_rds_disk_queue_depth_average"},
+		{cwName: "FreeableMemory", prometheusName: "node_memory_Cached_bytes"},
+	}
+
+	actual := mergeMetrics(known, []string{"FreeableMemory", "DBLoad", "CPUUtilization", "CheckpointLag", "DBLoad"})
+	var names []string
+	for _, m := range actual {
+		names = append(names, m.prometheusName)
+	}
+	expected := []string{"node_cpu_average", "node_memory_Cached_bytes", "aws_rds_checkpoint_lag_average", "aws_rds_db_load_average"}
+	assert.Equal(t, expected, names)
+}
+
+func TestDiscovery(t *testing.T) {
+	now := time.Date(2020, 12, 6, 10, 33, 0, 0, time.UTC)
+	instance := &sessions.Instance{Region: "us-east-1", Instance: "rds-mysql57"}
+	d := newDiscovery(0)
+	assert.Equal(t, DiscoverInterval, d.interval)
+
+	metrics, fresh := d.get(instance, now)
+	assert.Nil(t, metrics)
+	assert.False(t, fresh)
+
+	d.set(instance, []Metric{{cwName: "DBLoad"}}, now)
+	metrics, fresh = d.get(instance, now.Add(time.Minute))
+	assert.Len(t, metrics, 1)
+	assert.True(t, fresh)
+
+	metrics, fresh = d.get(instance, now.Add(DiscoverInterval))
+	assert.Len(t, metrics, 1)
+	assert.False(t, fresh)
+}
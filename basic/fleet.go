@@ -0,0 +1,247 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/percona/rds_exporter/sessions"
+)
+
+// fleetDimensions contains CloudWatch dimensions of fleet-level aggregates and their Prometheus labels.
+var fleetDimensions = []struct {
+	name  string
+	label string
+}{
+	{"DatabaseClass", "database_class"},
+	{"EngineName", "engine"},
+}
+
+// fleetName returns Prometheus metric name for given fleet-level metric statistic,
+// and a multiplier for converting CloudWatch values.
+func fleetName(metric Metric, statistic string, baseUnits bool) (string, float64) {
+	if baseUnits {
+		name, scale := baseUnitsName(metric, statistic)
+		return "aws_rds_fleet_" + strings.TrimPrefix(name, "aws_rds_"), scale
+	}
+
+	suffix := statisticSuffix(statistic)
+	if suffix == "" {
+		suffix = "average"
+	}
+	return "aws_rds_fleet_" + snakeCase(metric.cwName) + "_" + suffix, 1
+}
+
+// hasDimension returns true if metric is published with given CloudWatch dimension.
+func hasDimension(metric Metric, dimension string) bool {
+	for _, d := range metric.dimensions {
+		if d == dimension {
+			return true
+		}
+	}
+	return false
+}
+
+// FleetScraper retrieves metrics aggregated by DatabaseClass and EngineName dimensions
+// for every region and account of collector's sessions.
+type FleetScraper struct {
+	// params
+	collector *Collector
+
+	// internal
+	interval   time.Duration
+	m          sync.Mutex
+	accounts   map[*session.Session]string
+	aggregates map[string]*fleetAggregates // account/region -> discovered aggregates
+}
+
+// fleetAggregates contains queries for aggregates discovered with ListMetrics in a single account and region.
+type fleetAggregates struct {
+	queries []query
+	updated time.Time
+}
+
+func NewFleetScraper(collector *Collector) *FleetScraper {
+	interval := collector.config.Basic.DiscoverInterval
+	if interval == 0 {
+		interval = DiscoverInterval
+	}
+	return &FleetScraper{
+		// params
+		collector: collector,
+
+		// internal
+		interval:   interval,
+		accounts:   make(map[*session.Session]string),
+		aggregates: make(map[string]*fleetAggregates),
+	}
+}
+
+// account returns AWS account ID for given session.
+func (f *FleetScraper) account(ctx context.Context, sess *session.Session) (string, error) {
+	f.m.Lock()
+	defer f.m.Unlock()
+
+	if account, ok := f.accounts[sess]; ok {
+		return account, nil
+	}
+
+	out, err := sts.New(sess).GetCallerIdentityWithContext(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return "", err
+	}
+	account := aws.StringValue(out.Account)
+	f.accounts[sess] = account
+	return account, nil
+}
+
+// Scrape makes the required calls to AWS CloudWatch for every account and region once.
+// It returns metrics converted into Prometheus format.
+func (f *FleetScraper) Scrape(ctx context.Context) []prometheus.Metric {
+	metrics, ages := f.scrape(ctx)
+	if f.collector.config.Basic.DatapointAge {
+		metrics = append(metrics, ages.metrics(time.Now())...)
+	}
+	return metrics
+}
+
+// scrape returns metrics and the latest datapoint timestamps, so datapoint age can be calculated later.
+func (f *FleetScraper) scrape(ctx context.Context) ([]prometheus.Metric, datapointAges) {
+	var wg sync.WaitGroup
+	var m sync.Mutex
+	var res []prometheus.Metric
+	ages := make(datapointAges)
+	seen := make(map[string]struct{}) // account/region
+	for _, group := range f.collector.instancesBySession() {
+		sess := group.session
+		region := aws.StringValue(sess.Config.Region)
+		account, err := f.account(ctx, sess)
+		if err != nil {
+			level.Error(f.collector.l).Log("msg", "Failed to get AWS account for fleet metrics.", "error", err)
+			f.collector.status.Error(region, "", "", err)
+			continue
+		}
+		key := account + "/" + region
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			metrics, sessionAges := f.scrapeSession(ctx, sess, account, region)
+			m.Lock()
+			res = append(res, metrics...)
+			ages.merge(sessionAges)
+			m.Unlock()
+		}()
+	}
+	wg.Wait()
+
+	return res, ages
+}
+
+// scrapeSession gets values and the latest datapoint timestamps of fleet-level metrics
+// available in session's account and region.
+func (f *FleetScraper) scrapeSession(ctx context.Context, sess *session.Session, account, region string) ([]prometheus.Metric, datapointAges) {
+	s := NewScraper(sess, nil, f.collector)
+	queries := f.queries(ctx, s, account, region)
+
+	var res []prometheus.Metric
+	ages := make(datapointAges)
+	for _, batch := range makeBatches(queries, maxQueries) {
+		metrics, batchAges, _ := s.scrapeBatch(ctx, batch)
+		res = append(res, metrics...)
+		ages.merge(batchAges)
+	}
+	return res, ages
+}
+
+// queries returns queries for fleet-level metrics available in given account and region.
+// Available metrics are listed with ListMetrics once per discovery interval;
+// previously listed metrics are used if that fails.
+func (f *FleetScraper) queries(ctx context.Context, s *Scraper, account, region string) []query {
+	key := account + "/" + region
+	now := time.Now()
+	f.m.Lock()
+	cached := f.aggregates[key]
+	f.m.Unlock()
+	if cached != nil && now.Sub(cached.updated) < f.interval {
+		return cached.queries
+	}
+
+	known := make(map[string]Metric, len(f.collector.metrics))
+	for _, metric := range f.collector.metrics {
+		known[metric.cwName] = metric
+	}
+	t := getTiming(&f.collector.config.Basic, &sessions.Instance{})
+
+	var queries []query
+	var failed bool
+	for _, d := range fleetDimensions {
+		d := d
+		input := &cloudwatch.ListMetricsInput{
+			Namespace:      aws.String("AWS/RDS"),
+			Dimensions:     []*cloudwatch.DimensionFilter{{Name: aws.String(d.name)}},
+			RecentlyActive: aws.String(cloudwatch.RecentlyActivePt3h),
+		}
+		err := s.svc.ListMetricsPagesWithContext(ctx, input, func(page *cloudwatch.ListMetricsOutput, lastPage bool) bool {
+			for _, m := range page.Metrics {
+				metric, ok := known[aws.StringValue(m.MetricName)]
+				if !ok || len(m.Dimensions) != 1 || !hasDimension(metric, d.name) {
+					continue
+				}
+
+				constLabels := prometheus.Labels{
+					"region":         region,
+					"account":        account,
+					"database_class": "",
+					"engine":         "",
+				}
+				constLabels[d.label] = aws.StringValue(m.Dimensions[0].Value)
+				for _, statistic := range f.collector.statistics(metric) {
+					queries = append(queries, query{
+						dimensions:  m.Dimensions,
+						constLabels: constLabels,
+						timing:      t,
+						metric:      metric,
+						statistic:   statistic,
+					})
+				}
+			}
+
+			return true // continue pagination
+		})
+		if err != nil {
+			failed = true
+			level.Error(f.collector.l).Log("msg", fmt.Sprintf("Failed to list %s metrics in %s.", d.name, region), "error", err)
+			f.collector.status.Error(region, "", "", err)
+		}
+	}
+
+	if failed {
+		if cached != nil {
+			return cached.queries
+		}
+		return queries
+	}
+
+	f.m.Lock()
+	f.aggregates[key] = &fleetAggregates{
+		queries: queries,
+		updated: now,
+	}
+	f.m.Unlock()
+	return queries
+}
@@ -0,0 +1,101 @@
+package basic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/percona/exporter_shared/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
+)
+
+func TestFleetName(t *testing.T) {
+	cpu := Metric{cwName: "CPUUtilization", prometheusName: "node_cpu_average", unit: "Percent"}
+	latency := Metric{cwName: "CommitLatency", prometheusName: "aws_rds_commit_latency_average", unit: "Milliseconds"}
+
+	name, scale := fleetName(cpu, "Average", false)
+	assert.Equal(t, "aws_rds_fleet_cpu_utilization_average", name)
+	assert.Equal(t, 1.0, scale)
+
+	name, scale = fleetName(latency, "p99", false)
+	assert.Equal(t, "aws_rds_fleet_commit_latency_p99", name)
+	assert.Equal(t, 1.0, scale)
+
+	name, scale = fleetName(cpu, "Average", true)
+	assert.Equal(t, "aws_rds_fleet_cpu_utilization_ratio", name)
+	assert.Equal(t, 0.01, scale)
+
+	name, scale = fleetName(latency, "Maximum", true)
+	assert.Equal(t, "aws_rds_fleet_commit_latency_maximum_seconds", name)
+	assert.Equal(t, 0.001, scale)
+}
+
+func TestFleetMetrics(t *testing.T) {
+	var classes, engines int
+	for _, m := range Metrics {
+		if hasDimension(m, "DatabaseClass") {
+			classes++
+		}
+		if hasDimension(m, "EngineName") {
+			engines++
+		}
+	}
+	assert.NotZero(t, classes)
+	assert.Equal(t, len(Metrics), engines)
+}
+
+func TestFleetScraperCaches(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	cw := newFakeCloudWatch(t, 100, func(instance, metric, statistic string) fakeResult {
+		return fakeResult{timestamps: []time.Time{now.Add(-15 * time.Minute)}, values: []float64{42}}
+	})
+	cw.listed = map[string][]fakeListed{
+		"DatabaseClass": {{"CPUUtilization", "db.r5.large"}, {"UnknownMetric", "db.r5.large"}},
+		"EngineName":    {{"CPUUtilization", "mysql"}},
+	}
+
+	cpu := Metric{
+		cwName:         "CPUUtilization",
+		prometheusName: "node_cpu_average",
+		prometheusHelp: "The percentage of CPU utilization.",
+		unit:           "Percent",
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
+	}
+	c := newTestCollector(&config.Config{}, []Metric{cpu})
+	f := NewFleetScraper(c)
+	sess := cw.session()
+	ctx := context.Background()
+
+	for i := 0; i < 2; i++ {
+		account, err := f.account(ctx, sess)
+		require.NoError(t, err)
+		assert.Equal(t, "123456789012", account)
+
+		res, _ := f.scrapeSession(ctx, sess, account, "us-east-1")
+		metrics := helpers.ReadMetrics(res)
+		require.Len(t, metrics, 2)
+		for _, m := range metrics {
+			assert.Equal(t, "aws_rds_fleet_cpu_utilization_average", m.Name)
+			assert.Equal(t, 42.0, m.Value)
+		}
+	}
+	assert.Equal(t, 1, cw.calls("GetCallerIdentity"), "account should be cached")
+	assert.Equal(t, 2, cw.calls("ListMetrics"), "aggregates should be listed once per dimension")
+	assert.Equal(t, 2, cw.calls("GetMetricData"))
+
+	// previously listed aggregates are used if listing fails, and errors are tracked
+	f.aggregates["123456789012/us-east-1"].updated = time.Time{}
+	cw.listErr = "AccessDenied"
+	res, _ := f.scrapeSession(ctx, sess, "123456789012", "us-east-1")
+	metrics := helpers.ReadMetrics(res)
+	assert.Len(t, metrics, 2)
+	assert.Equal(t, 4, cw.calls("ListMetrics"))
+
+	statusMetrics := helpers.ReadMetrics(helpers.CollectMetrics(c.status))
+	assert.Equal(t, map[string]float64{
+		"rds_exporter_scrape_errors_total//AccessDenied": 2,
+	}, readMetrics(statusMetrics, "rds_exporter_scrape_errors_total"))
+}
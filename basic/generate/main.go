@@ -0,0 +1,28 @@
+// Command generate generates basic/metrics.go from basic/catalog.yml.
+// It is run with `go generate ./basic`.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+)
+
+func main() {
+	log.SetFlags(0)
+	catalogF := flag.String("catalog", "catalog.yml", "Catalog file name")
+	outputF := flag.String("output", "metrics.go", "Output file name")
+	flag.Parse()
+
+	c, err := readCatalog(*catalogF)
+	if err != nil {
+		log.Fatal(err)
+	}
+	b, err := render(c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err = os.WriteFile(*outputF, b, 0666); err != nil { //nolint:gosec
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestGenerated checks that basic/metrics.go is up to date with basic/catalog.yml.
+func TestGenerated(t *testing.T) {
+	c, err := readCatalog("../catalog.yml")
+	require.NoError(t, err)
+	expected, err := render(c)
+	require.NoError(t, err)
+
+	actual, err := os.ReadFile("../metrics.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(expected), string(actual), "basic/metrics.go is outdated, run `go generate ./basic`")
+}
+
+func TestHelp(t *testing.T) {
+	m := &metric{
+		Description: "The average amount of time taken per disk read I/O operation.\n",
+		Unit:        "Seconds",
+	}
+	assert.Equal(t, "The average amount of time taken per disk read I/O operation. Units: Seconds", m.Help())
+}
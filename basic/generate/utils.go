@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"os"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v2"
+)
+
+// metric represents a single catalog entry.
+type metric struct {
+	Name           string   `yaml:"name"`
+	PrometheusName string   `yaml:"prometheus_name"`
+	Unit           string   `yaml:"unit"`
+	Engines        []string `yaml:"engines"`
+	Dimensions     []string `yaml:"dimensions"`
+	Description    string   `yaml:"description"`
+}
+
+// Help returns Prometheus help text for the metric.
+func (m *metric) Help() string {
+	return strings.TrimSpace(m.Description) + " Units: " + m.Unit
+}
+
+// catalog represents catalog file. Engines and dimensions sections only hold YAML anchors.
+type catalog struct {
+	Engines    map[string][]string `yaml:"engines"`
+	Dimensions map[string][]string `yaml:"dimensions"`
+	Metrics    []metric            `yaml:"metrics"`
+}
+
+// knownUnits contains CloudWatch units used by RDS metrics.
+var knownUnits = map[string]struct{}{
+	"Count":        {},
+	"Count/Second": {},
+	"Seconds":      {},
+	"Milliseconds": {},
+	"Percent":      {},
+	"Bytes":        {},
+	"Bytes/Second": {},
+}
+
+// readCatalog reads and validates catalog file with given name.
+func readCatalog(filename string) (*catalog, error) {
+	b, err := os.ReadFile(filename) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+
+	var c catalog
+	if err = yaml.UnmarshalStrict(b, &c); err != nil {
+		return nil, fmt.Errorf("%s: %s", filename, err)
+	}
+
+	names := make(map[string]struct{}, len(c.Metrics))
+	for _, m := range c.Metrics {
+		if m.Name == "" || m.PrometheusName == "" || m.Description == "" {
+			return nil, fmt.Errorf("%s: metric %q: name, prometheus_name and description are required", filename, m.Name)
+		}
+		if _, ok := names[m.Name]; ok {
+			return nil, fmt.Errorf("%s: duplicate metric %q", filename, m.Name)
+		}
+		names[m.Name] = struct{}{}
+		if _, ok := knownUnits[m.Unit]; !ok {
+			return nil, fmt.Errorf("%s: metric %q: unknown unit %q", filename, m.Name, m.Unit)
+		}
+		if len(m.Engines) == 0 || len(m.Dimensions) == 0 {
+			return nil, fmt.Errorf("%s: metric %q: engines and dimensions are required", filename, m.Name)
+		}
+	}
+	return &c, nil
+}
+
+var metricsTemplate = template.Must(template.New("metrics").Funcs(template.FuncMap{
+	"quote": func(s string) string { return fmt.Sprintf("%q", s) },
+	"quoteAll": func(ss []string) string {
+		q := make([]string, len(ss))
+		for i, s := range ss {
+			q[i] = fmt.Sprintf("%q", s)
+		}
+		return strings.Join(q, ", ")
+	},
+}).Parse(`// Code generated by generate/main.go from catalog.yml; DO NOT EDIT.
+
+package basic
+
+var Metrics = []Metric{
+{{- range .Metrics }}
+	{
+		cwName:         {{ quote .Name }},
+		prometheusName: {{ quote .PrometheusName }},
+		prometheusHelp: {{ quote .Help }},
+		unit:           {{ quote .Unit }},
+		engines:        []string{ {{- quoteAll .Engines -}} },
+		dimensions:     []string{ {{- quoteAll .Dimensions -}} },
+	},
+{{- end }}
+}
+`))
+
+// render returns formatted Go source code for given catalog.
+func render(c *catalog) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := metricsTemplate.Execute(&buf, c); err != nil {
+		return nil, err
+	}
+	return format.Source(buf.Bytes())
+}
@@ -2,12 +2,22 @@ package basic
 
 import (
 	"bytes"
+	"encoding/xml"
 	"flag"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/stretchr/testify/require"
 )
 
@@ -31,3 +41,217 @@ func writeTestDataMetrics(t *testing.T, metrics []string) {
 	err := ioutil.WriteFile(filepath.Join("testdata", "all.txt"), b, 0666)
 	require.NoError(t, err)
 }
+
+// fakeResult contains fake CloudWatch datapoints for a single metric data query.
+type fakeResult struct {
+	status     string // Complete if empty
+	timestamps []time.Time
+	values     []float64
+}
+
+// fakeCloudWatch is a fake CloudWatch GetMetricData API server.
+// Results for each query are split into single-datapoint chunks, and responses contain at most pageSize chunks,
+// so results for the same query are split between pages like in the real API.
+// It also serves ListMetrics with listed metrics, and STS GetCallerIdentity.
+type fakeCloudWatch struct {
+	t        *testing.T
+	srv      *httptest.Server
+	pageSize int
+	results  func(instance, metric, statistic string) fakeResult
+
+	rw       sync.Mutex
+	listed   map[string][]fakeListed // dimension name -> listed metrics
+	listErr  string                  // ListMetrics error code, if not empty
+	actions  map[string]int          // API action -> number of requests
+	requests int                     // number of GetMetricData requests
+	queries  int
+}
+
+// newFakeCloudWatch starts a new fake CloudWatch server; it is stopped when the test ends.
+func newFakeCloudWatch(t *testing.T, pageSize int, results func(instance, metric, statistic string) fakeResult) *fakeCloudWatch {
+	f := &fakeCloudWatch{
+		t:        t,
+		pageSize: pageSize,
+		results:  results,
+		actions:  make(map[string]int),
+	}
+	f.srv = httptest.NewServer(http.HandlerFunc(f.handle))
+	t.Cleanup(f.srv.Close)
+	return f
+}
+
+// session returns AWS session for the fake server.
+func (f *fakeCloudWatch) session() *session.Session {
+	return session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-east-1"),
+		Endpoint:    aws.String(f.srv.URL),
+		Credentials: credentials.NewStaticCredentials("fake", "fake", ""),
+		MaxRetries:  aws.Int(0),
+	}))
+}
+
+type fakeMessage struct {
+	Code  string `xml:"Code"`
+	Value string `xml:"Value"`
+}
+
+type fakeMetricDataResult struct {
+	ID         string        `xml:"Id"`
+	StatusCode string        `xml:"StatusCode"`
+	Timestamps []string      `xml:"Timestamps>member"`
+	Values     []float64     `xml:"Values>member"`
+	Messages   []fakeMessage `xml:"Messages>member,omitempty"`
+}
+
+type fakeGetMetricDataResponse struct {
+	XMLName   xml.Name               `xml:"GetMetricDataResponse"`
+	Results   []fakeMetricDataResult `xml:"GetMetricDataResult>MetricDataResults>member"`
+	NextToken string                 `xml:"GetMetricDataResult>NextToken,omitempty"`
+}
+
+// fakeListed is a metric returned by fake ListMetrics with a single dimension.
+type fakeListed struct {
+	metric string
+	value  string // dimension value
+}
+
+type fakeDimension struct {
+	Name  string `xml:"Name"`
+	Value string `xml:"Value"`
+}
+
+type fakeListedMetric struct {
+	Namespace  string          `xml:"Namespace"`
+	MetricName string          `xml:"MetricName"`
+	Dimensions []fakeDimension `xml:"Dimensions>member"`
+}
+
+type fakeListMetricsResponse struct {
+	XMLName xml.Name           `xml:"ListMetricsResponse"`
+	Metrics []fakeListedMetric `xml:"ListMetricsResult>Metrics>member"`
+}
+
+type fakeGetCallerIdentityResponse struct {
+	XMLName xml.Name `xml:"GetCallerIdentityResponse"`
+	Account string   `xml:"GetCallerIdentityResult>Account"`
+}
+
+type fakeErrorResponse struct {
+	XMLName xml.Name `xml:"ErrorResponse"`
+	Code    string   `xml:"Error>Code"`
+	Message string   `xml:"Error>Message"`
+}
+
+// calls returns the number of requests with given API action.
+func (f *fakeCloudWatch) calls(action string) int {
+	f.rw.Lock()
+	defer f.rw.Unlock()
+	return f.actions[action]
+}
+
+func (f *fakeCloudWatch) handle(rw http.ResponseWriter, req *http.Request) {
+	require.NoError(f.t, req.ParseForm())
+	action := req.Form.Get("Action")
+	f.rw.Lock()
+	f.actions[action]++
+	f.rw.Unlock()
+
+	rw.Header().Set("Content-Type", "text/xml")
+	switch action {
+	case "GetMetricData":
+		f.getMetricData(rw, req)
+	case "ListMetrics":
+		f.listMetrics(rw, req)
+	case "GetCallerIdentity":
+		require.NoError(f.t, xml.NewEncoder(rw).Encode(fakeGetCallerIdentityResponse{Account: "123456789012"}))
+	default:
+		f.t.Errorf("unexpected action %q", action)
+		rw.WriteHeader(http.StatusBadRequest)
+	}
+}
+
+func (f *fakeCloudWatch) listMetrics(rw http.ResponseWriter, req *http.Request) {
+	f.rw.Lock()
+	listErr := f.listErr
+	f.rw.Unlock()
+	if listErr != "" {
+		rw.WriteHeader(http.StatusBadRequest)
+		require.NoError(f.t, xml.NewEncoder(rw).Encode(fakeErrorResponse{Code: listErr, Message: "fake error"}))
+		return
+	}
+
+	name := req.Form.Get("Dimensions.member.1.Name")
+	res := fakeListMetricsResponse{}
+	for _, m := range f.listed[name] {
+		res.Metrics = append(res.Metrics, fakeListedMetric{
+			Namespace:  "AWS/RDS",
+			MetricName: m.metric,
+			Dimensions: []fakeDimension{{Name: name, Value: m.value}},
+		})
+	}
+	require.NoError(f.t, xml.NewEncoder(rw).Encode(res))
+}
+
+func (f *fakeCloudWatch) getMetricData(rw http.ResponseWriter, req *http.Request) {
+
+	// make all result chunks for all queries
+	var chunks []fakeMetricDataResult
+	var queries int
+	for i := 1; ; i++ {
+		p := "MetricDataQueries.member." + strconv.Itoa(i) + "."
+		id := req.Form.Get(p + "Id")
+		if id == "" {
+			break
+		}
+		queries++
+		r := f.results(
+			req.Form.Get(p+"MetricStat.Metric.Dimensions.member.1.Value"),
+			req.Form.Get(p+"MetricStat.Metric.MetricName"),
+			req.Form.Get(p+"MetricStat.Stat"),
+		)
+		if r.status != "" && r.status != cloudwatch.StatusCodeComplete {
+			chunks = append(chunks, fakeMetricDataResult{
+				ID:         id,
+				StatusCode: r.status,
+				Messages:   []fakeMessage{{Code: r.status, Value: "fake error"}},
+			})
+			continue
+		}
+		for j := range r.timestamps {
+			status := cloudwatch.StatusCodePartialData
+			if j == len(r.timestamps)-1 {
+				status = cloudwatch.StatusCodeComplete
+			}
+			chunks = append(chunks, fakeMetricDataResult{
+				ID:         id,
+				StatusCode: status,
+				Timestamps: []string{r.timestamps[j].UTC().Format(time.RFC3339)},
+				Values:     []float64{r.values[j]},
+			})
+		}
+	}
+
+	f.rw.Lock()
+	f.requests++
+	if req.Form.Get("NextToken") == "" {
+		f.queries += queries
+	}
+	f.rw.Unlock()
+
+	var start int
+	if token := req.Form.Get("NextToken"); token != "" {
+		var err error
+		start, err = strconv.Atoi(token)
+		require.NoError(f.t, err)
+	}
+	res := fakeGetMetricDataResponse{}
+	end := start + f.pageSize
+	if end < len(chunks) {
+		res.NextToken = strconv.Itoa(end)
+	} else {
+		end = len(chunks)
+	}
+	res.Results = chunks[start:end]
+
+	require.NoError(f.t, xml.NewEncoder(rw).Encode(res))
+}
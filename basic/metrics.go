@@ -1,254 +1,406 @@
+// Code generated by generate/main.go from catalog.yml; DO NOT EDIT.
+
 package basic
 
 var Metrics = []Metric{
 	{
 		cwName:         "ActiveTransactions",
 		prometheusName: "aws_rds_active_transactions_average",
-		prometheusHelp: "ActiveTransactions",
+		prometheusHelp: "The average number of current transactions executing on an Aurora database instance per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "AuroraBinlogReplicaLag",
 		prometheusName: "aws_rds_aurora_binlog_replica_lag_average",
-		prometheusHelp: "AuroraBinlogReplicaLag",
+		prometheusHelp: "The amount of time a binary log replica DB cluster running on Aurora MySQL lags behind the binary log replication source. Units: Seconds",
+		unit:           "Seconds",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "AuroraReplicaLag",
 		prometheusName: "aws_rds_aurora_replica_lag_average",
-		prometheusHelp: "AuroraReplicaLag",
+		prometheusHelp: "For an Aurora Replica, the amount of lag when replicating updates from the primary instance. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "AuroraReplicaLagMaximum",
 		prometheusName: "aws_rds_aurora_replica_lag_maximum_average",
-		prometheusHelp: "AuroraReplicaLagMaximum",
+		prometheusHelp: "The maximum amount of lag between the primary instance and each Aurora DB instance in the DB cluster. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "AuroraReplicaLagMinimum",
 		prometheusName: "aws_rds_aurora_replica_lag_minimum_average",
-		prometheusHelp: "AuroraReplicaLagMinimum",
+		prometheusHelp: "The minimum amount of lag between the primary instance and each Aurora DB instance in the DB cluster. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "BinLogDiskUsage",
 		prometheusName: "aws_rds_bin_log_disk_usage_average",
 		prometheusHelp: "The amount of disk space occupied by binary logs on the master. Applies to MySQL read replicas. Units: Bytes",
+		unit:           "Bytes",
+		engines:        []string{"mysql", "mariadb", "aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "BlockedTransactions",
 		prometheusName: "aws_rds_blocked_transactions_average",
-		prometheusHelp: "BlockedTransactions",
+		prometheusHelp: "The average number of transactions in the database that are blocked per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "BufferCacheHitRatio",
 		prometheusName: "aws_rds_buffer_cache_hit_ratio_average",
-		prometheusHelp: "BufferCacheHitRatio",
+		prometheusHelp: "The percentage of requests that are served by the buffer cache. Units: Percent",
+		unit:           "Percent",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "BurstBalance",
 		prometheusName: "aws_rds_burst_balance_average",
 		prometheusHelp: "The percent of General Purpose SSD (gp2) burst-bucket I/O credits available. Units: Percent",
+		unit:           "Percent",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "CPUCreditBalance",
 		prometheusName: "aws_rds_cpu_credit_balance_average",
 		prometheusHelp: "[T2 instances] The number of CPU credits available for the instance to burst beyond its base CPU utilization. Credits are stored in the credit balance after they are earned and removed from the credit balance after they expire. Credits expire 24 hours after they are earned. CPU credit metrics are available only at a 5 minute frequency. Units: Count",
+		unit:           "Count",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "CPUCreditUsage",
 		prometheusName: "aws_rds_cpu_credit_usage_average",
 		prometheusHelp: "[T2 instances] The number of CPU credits consumed by the instance. One CPU credit equals one vCPU running at 100% utilization for one minute or an equivalent combination of vCPUs, utilization, and time (for example, one vCPU running at 50% utilization for two minutes or two vCPUs running at 25% utilization for two minutes). CPU credit metrics are available only at a 5 minute frequency. If you specify a period greater than five minutes, use the Sum statistic instead of the Average statistic. Units: Count",
+		unit:           "Count",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "CPUUtilization",
 		prometheusName: "node_cpu_average",
 		prometheusHelp: "The percentage of CPU utilization. Units: Percent",
+		unit:           "Percent",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "CommitLatency",
 		prometheusName: "aws_rds_commit_latency_average",
-		prometheusHelp: "CommitLatency",
+		prometheusHelp: "The average duration of commit operations. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "CommitThroughput",
 		prometheusName: "aws_rds_commit_throughput_average",
-		prometheusHelp: "CommitThroughput",
+		prometheusHelp: "The average number of commit operations per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "DDLLatency",
 		prometheusName: "aws_rds_ddl_latency_average",
-		prometheusHelp: "DDLLatency",
+		prometheusHelp: "The average duration of requests such as create, alter, and drop requests. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "DDLThroughput",
 		prometheusName: "aws_rds_ddl_throughput_average",
-		prometheusHelp: "DDLThroughput",
+		prometheusHelp: "The average number of DDL requests per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "DMLLatency",
 		prometheusName: "aws_rds_dml_latency_average",
-		prometheusHelp: "DMLLatency",
+		prometheusHelp: "The average duration of inserts, updates, and deletes. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "DMLThroughput",
 		prometheusName: "aws_rds_dml_throughput_average",
-		prometheusHelp: "DMLThroughput",
+		prometheusHelp: "The average number of inserts, updates, and deletes per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "DatabaseConnections",
 		prometheusName: "aws_rds_database_connections_average",
 		prometheusHelp: "The number of database connections in use. Units: Count",
+		unit:           "Count",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "Deadlocks",
 		prometheusName: "aws_rds_deadlocks_average",
-		prometheusHelp: "Deadlocks",
+		prometheusHelp: "The average number of deadlocks in the database per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "DeleteLatency",
 		prometheusName: "aws_rds_delete_latency_average",
-		prometheusHelp: "DeleteLatency",
+		prometheusHelp: "The average duration of delete queries. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "DeleteThroughput",
 		prometheusName: "aws_rds_delete_throughput_average",
-		prometheusHelp: "DeleteThroughput",
+		prometheusHelp: "The average number of delete queries per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "DiskQueueDepth",
 		prometheusName: "aws_rds_disk_queue_depth_average",
 		prometheusHelp: "The number of outstanding IOs (read/write requests) waiting to access the disk. Units: Count",
+		unit:           "Count",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "EngineUptime",
 		prometheusName: "node_boot_time_seconds",
-		prometheusHelp: "EngineUptime",
+		prometheusHelp: "The amount of time that the instance has been running. It is exposed as the engine start time. Units: Seconds",
+		unit:           "Seconds",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "FreeLocalStorage",
 		prometheusName: "aws_rds_free_local_storage_average",
-		prometheusHelp: "FreeLocalStorage",
+		prometheusHelp: "The amount of local storage available for temporary tables and logs. Units: Bytes",
+		unit:           "Bytes",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "FreeStorageSpace",
 		prometheusName: "node_filesystem_free_bytes",
 		prometheusHelp: "The amount of available storage space. Units: Bytes",
+		unit:           "Bytes",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "FreeableMemory",
 		prometheusName: "node_memory_Cached_bytes",
 		prometheusHelp: "The amount of available random access memory. Units: Bytes",
+		unit:           "Bytes",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "InsertLatency",
 		prometheusName: "aws_rds_insert_latency_average",
-		prometheusHelp: "InsertLatency",
+		prometheusHelp: "The average duration of insert queries. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "InsertThroughput",
 		prometheusName: "aws_rds_insert_throughput_average",
-		prometheusHelp: "InsertThroughput",
+		prometheusHelp: "The average number of insert queries per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "LoginFailures",
 		prometheusName: "aws_rds_login_failures_average",
-		prometheusHelp: "LoginFailures",
+		prometheusHelp: "The average number of failed login attempts per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "NetworkReceiveThroughput",
 		prometheusName: "aws_rds_network_receive_throughput_average",
-		prometheusHelp: "The incoming (Receive) network traffic on the DB instance, including both customer database traffic and Amazon RDS traffic used for monitoring and replication. Units: Bytes/second",
+		prometheusHelp: "The incoming (Receive) network traffic on the DB instance, including both customer database traffic and Amazon RDS traffic used for monitoring and replication. Units: Bytes/Second",
+		unit:           "Bytes/Second",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "NetworkThroughput",
 		prometheusName: "aws_rds_network_throughput_average",
-		prometheusHelp: "NetworkThroughput",
+		prometheusHelp: "The amount of network throughput both received from and transmitted to clients by each instance in the Aurora DB cluster. Units: Bytes/Second",
+		unit:           "Bytes/Second",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "NetworkTransmitThroughput",
 		prometheusName: "aws_rds_network_transmit_throughput_average",
-		prometheusHelp: "The outgoing (Transmit) network traffic on the DB instance, including both customer database traffic and Amazon RDS traffic used for monitoring and replication. Units: Bytes/second",
+		prometheusHelp: "The outgoing (Transmit) network traffic on the DB instance, including both customer database traffic and Amazon RDS traffic used for monitoring and replication. Units: Bytes/Second",
+		unit:           "Bytes/Second",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "Queries",
 		prometheusName: "aws_rds_queries_average",
-		prometheusHelp: "Queries",
+		prometheusHelp: "The average number of queries executed per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "ReadIOPS",
 		prometheusName: "aws_rds_read_iops_average",
-		prometheusHelp: "The average number of disk I/O operations per second. Units: Count/Second",
+		prometheusHelp: "The average number of disk read I/O operations per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "ReadLatency",
 		prometheusName: "aws_rds_read_latency_average",
-		prometheusHelp: "The average amount of time taken per disk I/O operation. Units: Seconds",
+		prometheusHelp: "The average amount of time taken per disk read I/O operation. Units: Seconds",
+		unit:           "Seconds",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "ReadThroughput",
 		prometheusName: "aws_rds_read_throughput_average",
 		prometheusHelp: "The average number of bytes read from disk per second. Units: Bytes/Second",
+		unit:           "Bytes/Second",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "ResultSetCacheHitRatio",
 		prometheusName: "aws_rds_result_set_cache_hit_ratio_average",
-		prometheusHelp: "ResultSetCacheHitRatio",
+		prometheusHelp: "The percentage of requests that are served by the result set cache. Units: Percent",
+		unit:           "Percent",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "SelectLatency",
 		prometheusName: "aws_rds_select_latency_average",
-		prometheusHelp: "SelectLatency",
+		prometheusHelp: "The average duration of select queries. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "SelectThroughput",
 		prometheusName: "aws_rds_select_throughput_average",
-		prometheusHelp: "SelectThroughput",
+		prometheusHelp: "The average number of select queries per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "SwapUsage",
 		prometheusName: "aws_rds_swap_usage_average",
 		prometheusHelp: "The amount of swap space used on the DB instance. Units: Bytes",
+		unit:           "Bytes",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "UpdateLatency",
 		prometheusName: "aws_rds_update_latency_average",
-		prometheusHelp: "UpdateLatency",
+		prometheusHelp: "The average duration of update queries. Units: Milliseconds",
+		unit:           "Milliseconds",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "UpdateThroughput",
 		prometheusName: "aws_rds_update_throughput_average",
-		prometheusHelp: "UpdateThroughput",
+		prometheusHelp: "The average number of update queries per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"aurora-mysql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DBClusterIdentifier", "Role", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "VolumeBytesUsed",
 		prometheusName: "aws_rds_volume_bytes_used_average",
-		prometheusHelp: "VolumeBytesUsed",
+		prometheusHelp: "The amount of storage used by the Aurora DB cluster. Units: Bytes",
+		unit:           "Bytes",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBClusterIdentifier", "EngineName"},
 	},
 	{
 		cwName:         "VolumeReadIOPs",
 		prometheusName: "aws_rds_volume_read_io_ps_average",
-		prometheusHelp: "VolumeReadIOPs",
+		prometheusHelp: "The number of billed read I/O operations from a cluster volume within a 5-minute interval. Units: Count",
+		unit:           "Count",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBClusterIdentifier", "EngineName"},
 	},
 	{
 		cwName:         "VolumeWriteIOPs",
 		prometheusName: "aws_rds_volume_write_io_ps_average",
-		prometheusHelp: "VolumeWriteIOPs",
+		prometheusHelp: "The number of write disk I/O operations to the cluster volume within a 5-minute interval. Units: Count",
+		unit:           "Count",
+		engines:        []string{"aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBClusterIdentifier", "EngineName"},
 	},
 	{
 		cwName:         "WriteIOPS",
 		prometheusName: "aws_rds_write_iops_average",
-		prometheusHelp: "The average number of disk I/O operations per second. Units: Count/Second",
+		prometheusHelp: "The average number of disk write I/O operations per second. Units: Count/Second",
+		unit:           "Count/Second",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "WriteLatency",
 		prometheusName: "aws_rds_write_latency_average",
-		prometheusHelp: "The average amount of time taken per disk I/O operation. Units: Seconds",
+		prometheusHelp: "The average amount of time taken per disk write I/O operation. Units: Seconds",
+		unit:           "Seconds",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "WriteThroughput",
 		prometheusName: "aws_rds_write_throughput_average",
 		prometheusHelp: "The average number of bytes written to disk per second. Units: Bytes/Second",
+		unit:           "Bytes/Second",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver", "aurora-mysql", "aurora-postgresql"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 	{
 		cwName:         "ReplicaLag",
 		prometheusName: "aws_rds_replica_lag",
-		prometheusHelp: "The amount of time a read replica DB instance lags behind the source DB instance. Unit: Seconds",
+		prometheusHelp: "The amount of time a read replica DB instance lags behind the source DB instance. Units: Seconds",
+		unit:           "Seconds",
+		engines:        []string{"mysql", "mariadb", "postgres", "oracle", "sqlserver"},
+		dimensions:     []string{"DBInstanceIdentifier", "DatabaseClass", "EngineName"},
 	},
 }
@@ -1,42 +1,208 @@
 package basic
 
 import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/aws/aws-sdk-go/aws/session"
 	"github.com/aws/aws-sdk-go/service/cloudwatch"
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
 	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/sessions"
 )
 
+// Default datapoints period, and delay and range of requested time interval.
+// They are used when not set in the configuration.
 var (
 	Period = 60 * time.Second
 	Delay  = 600 * time.Second
 	Range  = 600 * time.Second
 )
 
+// timing contains datapoints period and requested time interval parameters for a single instance.
+type timing struct {
+	period   time.Duration
+	delay    time.Duration
+	rng      time.Duration
+	adaptive bool
+}
+
+// getTiming returns timing for given instance: instance settings override global ones,
+// and both override defaults.
+func getTiming(cfg *config.Basic, instance *sessions.Instance) timing {
+	t := timing{
+		period:   Period,
+		delay:    Delay,
+		rng:      Range,
+		adaptive: cfg.Adaptive,
+	}
+	for _, d := range []struct {
+		dst         *time.Duration
+		global, own time.Duration
+	}{
+		{&t.period, cfg.Period, instance.BasicPeriod},
+		{&t.delay, cfg.Delay, instance.BasicDelay},
+		{&t.rng, cfg.Range, instance.BasicRange},
+	} {
+		if d.global != 0 {
+			*d.dst = d.global
+		}
+		if d.own != 0 {
+			*d.dst = d.own
+		}
+	}
+	if instance.BasicAdaptive != nil {
+		t.adaptive = *instance.BasicAdaptive
+	}
+
+	return t
+}
+
+// interval returns start and end of requested time interval.
+func (t timing) interval(now time.Time) (start, end time.Time) {
+	end = now
+	if !t.adaptive {
+		end = end.Add(-t.delay)
+	}
+	return end.Add(-t.rng), end
+}
+
+// Datapoint age metric name and help.
+const (
+	datapointAgeName = "aws_rds_datapoint_age_seconds"
+	datapointAgeHelp = "Age of the latest used CloudWatch datapoint, in seconds."
+)
+
+// datapointAge contains the latest datapoint timestamp of a single target metric.
+type datapointAge struct {
+	constLabels prometheus.Labels
+	metric      string
+	ts          time.Time
+}
+
+// datapointAges contains the latest datapoint timestamps by target and metric.
+// They are collected from all batches, so datapoint age is sent once for all metric statistics.
+type datapointAges map[string]datapointAge
+
+// add records datapoint timestamp of given query if it is newer than already recorded one.
+func (a datapointAges) add(q *query, ts time.Time) {
+	key := q.target() + "/" + q.metric.cwName
+	if age, ok := a[key]; ok && !age.ts.Before(ts) {
+		return
+	}
+	a[key] = datapointAge{constLabels: q.constLabels, metric: q.metric.cwName, ts: ts}
+}
+
+// merge adds all timestamps from other.
+func (a datapointAges) merge(other datapointAges) {
+	for key, age := range other {
+		if cur, ok := a[key]; ok && !cur.ts.Before(age.ts) {
+			continue
+		}
+		a[key] = age
+	}
+}
+
+// metrics returns datapoint age metrics relative to given time.
+func (a datapointAges) metrics(now time.Time) []prometheus.Metric {
+	res := make([]prometheus.Metric, 0, len(a))
+	for _, age := range a {
+		res = append(res, prometheus.MustNewConstMetric(
+			prometheus.NewDesc(datapointAgeName, datapointAgeHelp, []string{"metric"}, age.constLabels),
+			prometheus.GaugeValue,
+			now.Sub(age.ts).Seconds(),
+			age.metric,
+		))
+	}
+	return res
+}
+
+// maxQueries is the maximal number of metric queries in a single GetMetricData request.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_GetMetricData.html
+const maxQueries = 500
+
+// defaultStatistics contains statistics used for metrics without configured statistics.
+var defaultStatistics = []string{"Average"}
+
+// query represents a single metric statistic query for a single instance or fleet dimension.
+type query struct {
+	instance    *sessions.Instance // nil for fleet queries
+	dimensions  []*cloudwatch.Dimension
+	constLabels prometheus.Labels
+	timing      timing
+	metric      Metric
+	statistic   string
+}
+
+// target returns instance or dimensions of the query for logging.
+func (q *query) target() string {
+	if q.instance != nil {
+		return q.instance.String()
+	}
+
+	res := make([]string, len(q.dimensions))
+	for i, d := range q.dimensions {
+		res[i] = aws.StringValue(d.Name) + "=" + aws.StringValue(d.Value)
+	}
+	return strings.Join(res, ",")
+}
+
+// statisticSuffix returns Prometheus metric name suffix for given statistic; it is empty for Average.
+func statisticSuffix(statistic string) string {
+	switch statistic {
+	case "Average":
+		return ""
+	case "SampleCount":
+		return "sample_count"
+	default:
+		return strings.ReplaceAll(strings.ToLower(statistic), ".", "_")
+	}
+}
+
+// statisticName returns Prometheus metric name for given metric statistic.
+// Average statistic uses default metric name.
+func statisticName(metric Metric, statistic string) string {
+	suffix := statisticSuffix(statistic)
+	if suffix == "" {
+		return metric.prometheusName
+	}
+
+	return strings.TrimSuffix(metric.prometheusName, "_average") + "_" + suffix
+}
+
+// Scraper retrieves basic metrics for several RDS instances sharing a single session.
 type Scraper struct {
 	// params
-	instance  *config.Instance
+	session   *session.Session
+	instances []*sessions.Instance
 	collector *Collector
-	ch        chan<- prometheus.Metric
 
 	// internal
-	svc         *cloudwatch.CloudWatch
-	constLabels prometheus.Labels
+	svc *cloudwatch.CloudWatch
 }
 
-func NewScraper(instance *config.Instance, collector *Collector, ch chan<- prometheus.Metric) *Scraper {
-	// Create CloudWatch client
-	sess, _ := collector.sessions.GetSession(instance.Region, instance.Instance)
-	if sess == nil {
-		return nil
+func NewScraper(session *session.Session, instances []*sessions.Instance, collector *Collector) *Scraper {
+	return &Scraper{
+		// params
+		session:   session,
+		instances: instances,
+		collector: collector,
+
+		// internal
+		svc: cloudwatch.New(session),
 	}
-	svc := cloudwatch.New(sess)
+}
 
+// makeConstLabels returns constant labels for all instance's metrics.
+func makeConstLabels(instance *sessions.Instance) prometheus.Labels {
 	constLabels := prometheus.Labels{
 		"region":   instance.Region,
 		"instance": instance.Instance,
@@ -48,99 +214,264 @@ func NewScraper(instance *config.Instance, collector *Collector, ch chan<- prome
 			constLabels[n] = v
 		}
 	}
+	return constLabels
+}
 
-	return &Scraper{
-		// params
-		instance:  instance,
-		collector: collector,
-		ch:        ch,
-
-		// internal
-		svc:         svc,
-		constLabels: constLabels,
+// makeBatches splits queries into batches of at most size queries.
+func makeBatches(queries []query, size int) [][]query {
+	batches := make([][]query, 0, (len(queries)+size-1)/size)
+	for len(queries) > size {
+		batches = append(batches, queries[:size])
+		queries = queries[size:]
+	}
+	if len(queries) > 0 {
+		batches = append(batches, queries)
 	}
+	return batches
 }
 
-func getLatestDatapoint(datapoints []*cloudwatch.Datapoint) *cloudwatch.Datapoint {
-	var latest *cloudwatch.Datapoint = nil
-
-	for dp := range datapoints {
-		if latest == nil || latest.Timestamp.Before(*datapoints[dp].Timestamp) {
-			latest = datapoints[dp]
+// getLatestValue returns the latest timestamp and value, ignoring datapoints after given time if it is not zero.
+// It returns false if there are no values.
+func getLatestValue(timestamps []*time.Time, values []*float64, notAfter time.Time) (time.Time, float64, bool) {
+	var latestT time.Time
+	var latestV float64
+	var found bool
+	for i := range timestamps {
+		if i >= len(values) {
+			break
+		}
+		t := aws.TimeValue(timestamps[i])
+		if !notAfter.IsZero() && t.After(notAfter) {
+			continue
+		}
+		if !found || latestT.Before(t) {
+			latestT, latestV, found = t, aws.Float64Value(values[i]), true
 		}
 	}
 
-	return latest
+	return latestT, latestV, found
 }
 
 // Scrape makes the required calls to AWS CloudWatch by using the parameters in the Collector.
-// Once converted into Prometheus format, the metrics are pushed on the ch channel.
-func (s *Scraper) Scrape() {
+// It returns metrics converted into Prometheus format.
+func (s *Scraper) Scrape(ctx context.Context) []prometheus.Metric {
+	metrics, ages := s.scrape(ctx)
+	if s.collector.config.Basic.DatapointAge {
+		metrics = append(metrics, ages.metrics(time.Now())...)
+	}
+	return metrics
+}
+
+// scrape returns metrics and the latest datapoint timestamps, so datapoint age can be calculated later.
+func (s *Scraper) scrape(ctx context.Context) ([]prometheus.Metric, datapointAges) {
+	// queries with the same time interval parameters can be batched
+	type intervalKey struct {
+		delay, rng time.Duration
+		adaptive   bool
+	}
+	var intervals []intervalKey
+	queries := make(map[intervalKey][]query)
+	instanceMetrics := s.allInstanceMetrics(ctx)
+	for _, instance := range s.instances {
+		constLabels := makeConstLabels(instance)
+		dimensions := []*cloudwatch.Dimension{{
+			Name:  aws.String("DBInstanceIdentifier"),
+			Value: aws.String(instance.Instance),
+		}}
+		t := getTiming(&s.collector.config.Basic, instance)
+		key := intervalKey{delay: t.delay, rng: t.rng, adaptive: t.adaptive}
+		if queries[key] == nil {
+			intervals = append(intervals, key)
+		}
+		for _, metric := range instanceMetrics[instance] {
+			for _, statistic := range s.collector.statistics(metric) {
+				queries[key] = append(queries[key], query{
+					instance:    instance,
+					dimensions:  dimensions,
+					constLabels: constLabels,
+					timing:      t,
+					metric:      metric,
+					statistic:   statistic,
+				})
+			}
+		}
+	}
+
 	var wg sync.WaitGroup
-	defer wg.Wait()
+	var m sync.Mutex
+	var res []prometheus.Metric
+	ages := make(datapointAges)
+	failedInstances := make(map[*sessions.Instance]struct{})
+	for _, key := range intervals {
+		for _, batch := range makeBatches(queries[key], maxQueries) {
+			batch := batch
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
 
-	wg.Add(len(s.collector.metrics))
-	for _, metric := range s.collector.metrics {
-		metric := metric
-		go func() {
-			defer wg.Done()
+				metrics, batchAges, failed := s.scrapeBatch(ctx, batch)
+				m.Lock()
+				res = append(res, metrics...)
+				ages.merge(batchAges)
+				for instance := range failed {
+					failedInstances[instance] = struct{}{}
+				}
+				m.Unlock()
+			}()
+		}
+	}
+	wg.Wait()
 
-			if err := s.scrapeMetric(metric); err != nil {
-				level.Error(s.collector.l).Log("metric", metric.cwName, "error", err)
-			}
-		}()
+	for _, instance := range s.instances {
+		_, failed := failedInstances[instance]
+		s.collector.status.Done(instance.Region, instance.Instance, !failed)
 	}
+
+	return res, ages
 }
 
-func (s *Scraper) scrapeMetric(metric Metric) error {
+// scrapeBatch gets metrics for given queries with paginated GetMetricData requests.
+// All queries should have the same time interval parameters.
+// It returns metrics, the latest datapoint timestamps, and instances with errors; errors are logged and tracked.
+func (s *Scraper) scrapeBatch(ctx context.Context, queries []query) ([]prometheus.Metric, datapointAges, map[*sessions.Instance]struct{}) {
 	now := time.Now()
-	end := now.Add(-Delay)
+	start, end := queries[0].timing.interval(now)
 
-	params := &cloudwatch.GetMetricStatisticsInput{
-		EndTime:   aws.Time(end),
-		StartTime: aws.Time(end.Add(-Range)),
-
-		Period:     aws.Int64(int64(Period.Seconds())),
-		MetricName: aws.String(metric.cwName),
-		Namespace:  aws.String("AWS/RDS"),
-		Dimensions: []*cloudwatch.Dimension{},
-		Statistics: aws.StringSlice([]string{"Average"}),
-		Unit:       nil,
+	input := &cloudwatch.GetMetricDataInput{
+		EndTime:           aws.Time(end),
+		StartTime:         aws.Time(start),
+		ScanBy:            aws.String(cloudwatch.ScanByTimestampDescending),
+		MetricDataQueries: make([]*cloudwatch.MetricDataQuery, len(queries)),
+	}
+	ids := make(map[string]int, len(queries)) // query ID -> index
+	for i, q := range queries {
+		id := "m" + strconv.Itoa(i)
+		ids[id] = i
+		input.MetricDataQueries[i] = &cloudwatch.MetricDataQuery{
+			Id: aws.String(id),
+			MetricStat: &cloudwatch.MetricStat{
+				Metric: &cloudwatch.Metric{
+					Namespace:  aws.String("AWS/RDS"),
+					MetricName: aws.String(q.metric.cwName),
+					Dimensions: q.dimensions,
+				},
+				Period: aws.Int64(int64(q.timing.period.Seconds())),
+				Stat:   aws.String(q.statistic),
+			},
+		}
 	}
 
-	params.Dimensions = append(params.Dimensions, &cloudwatch.Dimension{
-		Name:  aws.String("DBInstanceIdentifier"),
-		Value: aws.String(s.instance.Instance),
-	})
+	// Call CloudWatch to gather the datapoints; results for the same query may be split between pages
+	timestamps := make(map[string][]*time.Time, len(queries))
+	values := make(map[string][]*float64, len(queries))
+	failed := make(map[string]struct{})
+	failedInstances := make(map[*sessions.Instance]struct{})
+	err := s.svc.GetMetricDataPagesWithContext(ctx, input, func(page *cloudwatch.GetMetricDataOutput, lastPage bool) bool {
+		for _, m := range page.Messages {
+			level.Warn(s.collector.l).Log("msg", aws.StringValue(m.Value), "code", aws.StringValue(m.Code))
+		}
+
+		for _, r := range page.MetricDataResults {
+			id := aws.StringValue(r.Id)
+			i, ok := ids[id]
+			if !ok {
+				level.Error(s.collector.l).Log("msg", fmt.Sprintf("Unexpected metric data result ID %q.", id))
+				continue
+			}
+			timestamps[id] = append(timestamps[id], r.Timestamps...)
+			values[id] = append(values[id], r.Values...)
 
-	// Call CloudWatch to gather the datapoints
-	resp, err := s.svc.GetMetricStatistics(params)
+			switch code := aws.StringValue(r.StatusCode); code {
+			case cloudwatch.StatusCodeComplete, cloudwatch.StatusCodePartialData:
+				// PartialData means that the rest is on the next pages
+			default:
+				q := queries[i]
+				failed[id] = struct{}{}
+				msg := code
+				for _, m := range r.Messages {
+					msg += ": " + aws.StringValue(m.Code) + " " + aws.StringValue(m.Value)
+				}
+				err := awserr.New(code, msg, nil)
+				level.Error(s.collector.l).Log("target", q.target(), "metric", q.metric.cwName, "statistic", q.statistic, "error", err)
+				if q.instance != nil {
+					failedInstances[q.instance] = struct{}{}
+					s.collector.status.Error(q.instance.Region, q.instance.Instance, q.metric.cwName, err)
+				} else {
+					s.collector.status.Error(q.constLabels["region"], "", q.metric.cwName, err)
+				}
+			}
+		}
+
+		return true // continue pagination
+	})
 	if err != nil {
-		return err
+		level.Error(s.collector.l).Log("msg", fmt.Sprintf("Failed to get %d metrics.", len(queries)), "error", err)
+		if q := queries[0]; q.instance == nil {
+			// all fleet queries of a batch are for the same account and region
+			s.collector.status.Error(q.constLabels["region"], "", "", err)
+		}
+		for _, q := range queries {
+			if q.instance == nil {
+				continue
+			}
+			if _, ok := failedInstances[q.instance]; ok {
+				continue
+			}
+			failedInstances[q.instance] = struct{}{}
+			s.collector.status.Error(q.instance.Region, q.instance.Instance, "", err)
+		}
+		return nil, nil, failedInstances
 	}
 
-	// There's nothing in there, don't publish the metric
-	if len(resp.Datapoints) == 0 {
-		return nil
-	}
+	res := make([]prometheus.Metric, 0, len(queries))
+	ages := make(datapointAges)
+	for i, q := range queries {
+		id := "m" + strconv.Itoa(i)
+		if _, ok := failed[id]; ok {
+			continue
+		}
 
-	// Pick the latest datapoint
-	dp := getLatestDatapoint(resp.Datapoints)
+		// datapoint timestamp is the period start; in adaptive mode, skip the current incomplete period
+		var notAfter time.Time
+		if q.timing.adaptive {
+			notAfter = now.Add(-q.timing.period)
+		}
 
-	// Get the metric.
-	v := aws.Float64Value(dp.Average)
-	switch metric.cwName {
-	case "EngineUptime":
-		// "Fake EngineUptime -> node_boot_time with time.Now().Unix() - EngineUptime."
-		v = float64(time.Now().Unix() - int64(v))
-	}
+		// There's nothing in there, don't publish the metric
+		ts, v, ok := getLatestValue(timestamps[id], values[id], notAfter)
+		if !ok {
+			continue
+		}
+
+		name := statisticName(q.metric, q.statistic)
+		switch {
+		case q.instance == nil:
+			var scale float64
+			name, scale = fleetName(q.metric, q.statistic, s.collector.config.Basic.BaseUnits)
+			v *= scale
+		case q.metric.cwName == "EngineUptime" && q.statistic != "SampleCount" && q.statistic != "Sum":
+			// "Fake EngineUptime -> node_boot_time with time.Now().Unix() - EngineUptime."
+			// Boot time is already in base units, so it is kept with base units too.
+			v = float64(time.Now().Unix() - int64(v))
+		case s.collector.config.Basic.BaseUnits:
+			var scale float64
+			name, scale = baseUnitsName(q.metric, q.statistic)
+			v *= scale
+		}
 
-	// Send metric.
-	s.ch <- prometheus.MustNewConstMetric(
-		prometheus.NewDesc(metric.prometheusName, metric.prometheusHelp, nil, s.constLabels),
-		prometheus.GaugeValue,
-		v,
-	)
+		// Send metric.
+		m := prometheus.MustNewConstMetric(
+			prometheus.NewDesc(name, q.metric.prometheusHelp, nil, q.constLabels),
+			prometheus.GaugeValue,
+			v,
+		)
+		if s.collector.config.Basic.Timestamps {
+			m = prometheus.NewMetricWithTimestamp(ts, m)
+		}
+		res = append(res, m)
+
+		ages.add(&q, ts)
+	}
 
-	return nil
+	return res, ages, failedInstances
 }
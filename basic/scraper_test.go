@@ -0,0 +1,257 @@
+package basic
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/go-kit/log"
+	"github.com/percona/exporter_shared/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/sessions"
+	"github.com/percona/rds_exporter/status"
+)
+
+// newTestCollector returns collector for tests with given metrics and configuration without sessions.
+func newTestCollector(cfg *config.Config, metrics []Metric) *Collector {
+	return &Collector{
+		config:   cfg,
+		sessions: &sessions.Sessions{},
+		metrics:  metrics,
+		status:   status.New("basic"),
+		l:        log.NewNopLogger(),
+	}
+}
+
+// testMetrics contains a few metrics for tests.
+var testMetrics = []Metric{
+	{cwName: "CPUUtilization", prometheusName: "node_cpu_average", prometheusHelp: "The percentage of CPU utilization.", unit: "Percent"},
+	{cwName: "FreeableMemory", prometheusName: "node_memory_Cached_bytes", prometheusHelp: "The amount of available RAM.", unit: "Bytes"},
+}
+
+// readMetrics returns metric values by name and instance label.
+func readMetrics(metrics []*helpers.Metric, names ...string) map[string]float64 {
+	res := make(map[string]float64)
+	for _, m := range metrics {
+		for _, n := range names {
+			if m.Name == n {
+				key := m.Name + "/" + m.Labels["instance"]
+				if metric := m.Labels["metric"]; metric != "" {
+					key += "/" + metric
+				}
+				if code := m.Labels["code"]; code != "" {
+					key += "/" + code
+				}
+				res[key] = m.Value
+			}
+		}
+	}
+	return res
+}
+
+func TestMakeBatches(t *testing.T) {
+	queries := make([]query, 1001)
+	for i := range queries {
+		queries[i].metric.cwName = "CPUUtilization"
+	}
+
+	batches := makeBatches(queries, maxQueries)
+	assert.Len(t, batches, 3)
+	assert.Len(t, batches[0], 500)
+	assert.Len(t, batches[1], 500)
+	assert.Len(t, batches[2], 1)
+
+	assert.Len(t, makeBatches(queries[:500], maxQueries), 1)
+	assert.Len(t, makeBatches(nil, maxQueries), 0)
+}
+
+func TestGetLatestValue(t *testing.T) {
+	ts := time.Date(2020, 12, 6, 10, 33, 0, 0, time.UTC)
+
+	_, _, ok := getLatestValue(nil, nil, time.Time{})
+	assert.False(t, ok)
+
+	// results from different pages may be unordered
+	timestamps := aws.TimeSlice([]time.Time{ts.Add(-time.Minute), ts, ts.Add(-2 * time.Minute)})
+	values := aws.Float64Slice([]float64{1, 2, 3})
+	latestT, latestV, ok := getLatestValue(timestamps, values, time.Time{})
+	assert.True(t, ok)
+	assert.Equal(t, ts, latestT)
+	assert.Equal(t, 2.0, latestV)
+
+	// skip incomplete period
+	latestT, latestV, ok = getLatestValue(timestamps, values, ts.Add(-30*time.Second))
+	assert.True(t, ok)
+	assert.Equal(t, ts.Add(-time.Minute), latestT)
+	assert.Equal(t, 1.0, latestV)
+}
+
+func TestGetTiming(t *testing.T) {
+	adaptive, notAdaptive := true, false
+	now := time.Date(2020, 12, 6, 10, 33, 0, 0, time.UTC)
+
+	timing := getTiming(&config.Basic{}, &sessions.Instance{})
+	assert.Equal(t, Period, timing.period)
+	assert.False(t, timing.adaptive)
+	start, end := timing.interval(now)
+	assert.Equal(t, now.Add(-Delay-Range), start)
+	assert.Equal(t, now.Add(-Delay), end)
+
+	cfg := &config.Basic{Period: 5 * time.Minute, Range: 15 * time.Minute, Adaptive: true}
+	timing = getTiming(cfg, &sessions.Instance{BasicPeriod: time.Minute})
+	assert.Equal(t, time.Minute, timing.period)
+	assert.Equal(t, 15*time.Minute, timing.rng)
+	assert.True(t, timing.adaptive)
+	start, end = timing.interval(now)
+	assert.Equal(t, now.Add(-15*time.Minute), start)
+	assert.Equal(t, now, end)
+
+	timing = getTiming(cfg, &sessions.Instance{BasicDelay: time.Minute, BasicAdaptive: &notAdaptive})
+	assert.Equal(t, 5*time.Minute, timing.period)
+	assert.False(t, timing.adaptive)
+	start, end = timing.interval(now)
+	assert.Equal(t, now.Add(-16*time.Minute), start)
+	assert.Equal(t, now.Add(-time.Minute), end)
+
+	timing = getTiming(&config.Basic{}, &sessions.Instance{BasicAdaptive: &adaptive})
+	assert.True(t, timing.adaptive)
+}
+
+func TestStatisticName(t *testing.T) {
+	readLatency := Metric{cwName: "ReadLatency", prometheusName: "aws_rds_read_latency_average"}
+	replicaLag := Metric{cwName: "ReplicaLag", prometheusName: "aws_rds_replica_lag"}
+
+	for _, td := range []struct {
+		metric    Metric
+		statistic string
+		expected  string
+	}{
+		{readLatency, "Average", "aws_rds_read_latency_average"},
+		{readLatency, "Maximum", "aws_rds_read_latency_maximum"},
+		{readLatency, "SampleCount", "aws_rds_read_latency_sample_count"},
+		{readLatency, "p99", "aws_rds_read_latency_p99"},
+		{readLatency, "p99.9", "aws_rds_read_latency_p99_9"},
+		{replicaLag, "Average", "aws_rds_replica_lag"},
+		{replicaLag, "Minimum", "aws_rds_replica_lag_minimum"},
+	} {
+		assert.Equal(t, td.expected, statisticName(td.metric, td.statistic))
+	}
+}
+
+func TestScraperPagesAndPartialErrors(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	values := map[string]float64{
+		"rds-a/CPUUtilization": 10,
+		"rds-a/FreeableMemory": 1000,
+		"rds-b/CPUUtilization": 20,
+	}
+	cw := newFakeCloudWatch(t, 1, func(instance, metric, statistic string) fakeResult {
+		v, ok := values[instance+"/"+metric]
+		if !ok {
+			return fakeResult{status: "InternalError"}
+		}
+		// the newest datapoint is the second one, and it is returned on the second page
+		return fakeResult{
+			timestamps: []time.Time{now.Add(-15 * time.Minute), now.Add(-14 * time.Minute)},
+			values:     []float64{v - 1, v},
+		}
+	})
+
+	c := newTestCollector(&config.Config{}, testMetrics)
+	instances := []*sessions.Instance{
+		{Region: "us-east-1", Instance: "rds-a"},
+		{Region: "us-east-1", Instance: "rds-b"},
+	}
+	s := NewScraper(cw.session(), instances, c)
+	metrics := helpers.ReadMetrics(s.Scrape(context.Background()))
+
+	assert.Equal(t, map[string]float64{
+		"node_cpu_average/rds-a":         10,
+		"node_memory_Cached_bytes/rds-a": 1000,
+		"node_cpu_average/rds-b":         20,
+	}, readMetrics(metrics, "node_cpu_average", "node_memory_Cached_bytes"))
+	assert.Equal(t, 7, cw.requests, "each datapoint and error should be returned on a separate page")
+	assert.Equal(t, 4, cw.queries)
+
+	statusMetrics := helpers.ReadMetrics(helpers.CollectMetrics(c.status))
+	assert.Equal(t, map[string]float64{
+		"rds_exporter_scrape_success/rds-a": 1,
+		"rds_exporter_scrape_success/rds-b": 0,
+	}, readMetrics(statusMetrics, "rds_exporter_scrape_success"))
+	assert.Equal(t, map[string]float64{
+		"rds_exporter_scrape_errors_total/rds-b/FreeableMemory/InternalError": 1,
+	}, readMetrics(statusMetrics, "rds_exporter_scrape_errors_total"))
+}
+
+func TestScraperDatapointAgeAcrossBatches(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	statistics := make([]string, maxQueries+100)
+	for i := range statistics {
+		statistics[i] = fmt.Sprintf("p%.1f", float64(i+1)/10)
+	}
+	cw := newFakeCloudWatch(t, 1000, func(instance, metric, statistic string) fakeResult {
+		// the newest datapoint is returned only for statistics in the second batch
+		ts := now.Add(-15 * time.Minute)
+		if statistic == statistics[len(statistics)-1] {
+			ts = now.Add(-14 * time.Minute)
+		}
+		return fakeResult{timestamps: []time.Time{ts}, values: []float64{1}}
+	})
+
+	cfg := &config.Config{Basic: config.Basic{
+		DatapointAge: true,
+		Statistics:   map[string][]string{"CPUUtilization": statistics},
+	}}
+	c := newTestCollector(cfg, testMetrics[:1])
+	instances := []*sessions.Instance{{Region: "us-east-1", Instance: "rds-a"}}
+	s := NewScraper(cw.session(), instances, c)
+	metrics := helpers.ReadMetrics(s.Scrape(context.Background()))
+
+	assert.Equal(t, 2, cw.requests, "queries should be split into two batches")
+	assert.Equal(t, len(statistics), cw.queries)
+
+	var ages []float64
+	for _, m := range metrics {
+		if m.Name == datapointAgeName {
+			ages = append(ages, m.Value)
+		}
+	}
+	require.Len(t, ages, 1, "datapoint age should be sent once for all batches")
+	assert.InDelta(t, time.Since(now.Add(-14*time.Minute)).Seconds(), ages[0], 5)
+}
+
+func TestScraperDiscover(t *testing.T) {
+	now := time.Now().Truncate(time.Minute)
+	cw := newFakeCloudWatch(t, 1000, func(instance, metric, statistic string) fakeResult {
+		return fakeResult{timestamps: []time.Time{now.Add(-15 * time.Minute)}, values: []float64{1}}
+	})
+	cw.listed = map[string][]fakeListed{
+		"DBInstanceIdentifier": {
+			{metric: "CPUUtilization", value: "rds-a"},
+			{metric: "Replica-Lag.Total/Sec Count", value: "rds-a"},
+		},
+	}
+
+	c := newTestCollector(&config.Config{}, testMetrics)
+	c.discovery = newDiscovery(0)
+	var instances []*sessions.Instance
+	for i := 0; i < 2*maxDiscoveries; i++ {
+		instances = append(instances, &sessions.Instance{Region: "us-east-1", Instance: fmt.Sprintf("rds-%d", i)})
+	}
+	s := NewScraper(cw.session(), instances, c)
+	metrics := helpers.ReadMetrics(s.Scrape(context.Background()))
+
+	assert.Equal(t, len(instances), cw.calls("ListMetrics"))
+	assert.Len(t, readMetrics(metrics, "node_cpu_average"), len(instances))
+	assert.Len(t, readMetrics(metrics, "aws_rds_replica_lag_total_sec_count_average"), len(instances),
+		"invalid characters in discovered names should be replaced")
+
+	// discovered metrics are cached
+	s.Scrape(context.Background())
+	assert.Equal(t, len(instances), cw.calls("ListMetrics"))
+}
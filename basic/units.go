@@ -0,0 +1,90 @@
+package basic
+
+import (
+	"strings"
+	"unicode"
+)
+
+// baseUnit contains Prometheus metric name suffix and value multiplier for a single CloudWatch unit.
+type baseUnit struct {
+	suffix string
+	scale  float64
+}
+
+// baseUnits maps CloudWatch units to Prometheus base units.
+var baseUnits = map[string]baseUnit{
+	"Count":        {"", 1},
+	"Count/Second": {"_per_second", 1},
+	"Seconds":      {"_seconds", 1},
+	"Milliseconds": {"_seconds", 0.001},
+	"Percent":      {"_ratio", 0.01},
+	"Bytes":        {"_bytes", 1},
+	"Bytes/Second": {"_bytes_per_second", 1},
+}
+
+// snakeCase converts CloudWatch metric name to snake case: "ReadIOPS" -> "read_iops", "CPUUtilization" -> "cpu_utilization",
+// "EBSIOBalance%" -> "ebsio_balance_percent". Other characters invalid in Prometheus metric names are replaced with "_".
+func snakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if i > 0 && unicode.IsUpper(r) {
+			prevLower := unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1])
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || (unicode.IsUpper(runes[i-1]) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return strings.Map(func(r rune) rune {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' || r == ':' {
+			return r
+		}
+		return '_'
+	}, strings.ReplaceAll(b.String(), "%", "_percent"))
+}
+
+// baseUnitsName returns Prometheus metric name in base units for given metric statistic,
+// and a multiplier for converting CloudWatch values, for example, "aws_rds_read_latency_seconds" and 1
+// or "aws_rds_commit_latency_p99_seconds" and 0.001.
+// Average statistic does not have a suffix, except for metrics named after other metric's statistic:
+// AuroraReplicaLag Maximum is "aws_rds_aurora_replica_lag_maximum_seconds",
+// so AuroraReplicaLagMaximum Average is "aws_rds_aurora_replica_lag_maximum_average_seconds".
+// SampleCount statistic is a plain number of datapoints and is not converted.
+func baseUnitsName(metric Metric, statistic string) (string, float64) {
+	name := "aws_rds_" + snakeCase(metric.cwName)
+	suffix := statisticSuffix(statistic)
+	if suffix == "" && isStatisticMetric(metric.cwName) {
+		suffix = "average"
+	}
+	if suffix != "" {
+		name += "_" + suffix
+	}
+	if statistic == "SampleCount" {
+		return name, 1
+	}
+
+	unit, ok := baseUnits[metric.unit]
+	if !ok {
+		return name, 1
+	}
+	return name + unit.suffix, unit.scale
+}
+
+// isStatisticMetric returns true if CloudWatch metric name is a name of another catalog metric
+// followed by a statistic, like AuroraReplicaLagMaximum.
+func isStatisticMetric(cwName string) bool {
+	for _, statistic := range []string{"Maximum", "Minimum", "Sum", "SampleCount"} {
+		prefix := strings.TrimSuffix(cwName, statistic)
+		if prefix == cwName {
+			continue
+		}
+		for _, m := range Metrics {
+			if m.cwName == prefix {
+				return true
+			}
+		}
+	}
+	return false
+}
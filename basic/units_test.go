@@ -0,0 +1,71 @@
+package basic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSnakeCase(t *testing.T) {
+	for _, m := range Metrics {
+		if !strings.HasPrefix(m.prometheusName, "aws_rds_") || !strings.HasSuffix(m.prometheusName, "_average") {
+			continue
+		}
+		expected := strings.TrimSuffix(strings.TrimPrefix(m.prometheusName, "aws_rds_"), "_average")
+		assert.Equal(t, expected, snakeCase(m.cwName), "%s", m.cwName)
+	}
+
+	assert.Equal(t, "cpu_utilization", snakeCase("CPUUtilization"))
+	assert.Equal(t, "engine_uptime", snakeCase("EngineUptime"))
+}
+
+func TestBaseUnitsName(t *testing.T) {
+	for _, tc := range []struct {
+		cwName    string
+		unit      string
+		statistic string
+		name      string
+		scale     float64
+	}{
+		{"ReadLatency", "Seconds", "Average", "aws_rds_read_latency_seconds", 1},
+		{"ReadLatency", "Seconds", "Maximum", "aws_rds_read_latency_maximum_seconds", 1},
+		{"CommitLatency", "Milliseconds", "p99.9", "aws_rds_commit_latency_p99_9_seconds", 0.001},
+		{"CommitLatency", "Milliseconds", "SampleCount", "aws_rds_commit_latency_sample_count", 1},
+		{"FreeStorageSpace", "Bytes", "Average", "aws_rds_free_storage_space_bytes", 1},
+		{"CPUUtilization", "Percent", "Maximum", "aws_rds_cpu_utilization_maximum_ratio", 0.01},
+		{"ReadThroughput", "Bytes/Second", "Average", "aws_rds_read_throughput_bytes_per_second", 1},
+		{"Queries", "Count/Second", "Average", "aws_rds_queries_per_second", 1},
+		{"DatabaseConnections", "Count", "Average", "aws_rds_database_connections", 1},
+	} {
+		name, scale := baseUnitsName(Metric{cwName: tc.cwName, unit: tc.unit}, tc.statistic)
+		assert.Equal(t, tc.name, name)
+		assert.Equal(t, tc.scale, scale)
+	}
+}
+
+func TestBaseUnitsNameCollisions(t *testing.T) {
+	statistics := []string{"SampleCount", "Average", "Sum", "Minimum", "Maximum", "p99", "p99.9"}
+	names := make(map[string]string)
+	for _, m := range Metrics {
+		for _, statistic := range statistics {
+			name, _ := baseUnitsName(m, statistic)
+			metric := m.cwName + " " + statistic
+			if other, ok := names[name]; ok {
+				t.Errorf("%s and %s have the same name %s", other, metric, name)
+			}
+			names[name] = metric
+		}
+	}
+
+	lag := Metric{cwName: "AuroraReplicaLag", unit: "Milliseconds"}
+	lagMaximum := Metric{cwName: "AuroraReplicaLagMaximum", unit: "Milliseconds"}
+	name, _ := baseUnitsName(lag, "Average")
+	assert.Equal(t, "aws_rds_aurora_replica_lag_seconds", name)
+	name, _ = baseUnitsName(lag, "Maximum")
+	assert.Equal(t, "aws_rds_aurora_replica_lag_maximum_seconds", name)
+	name, _ = baseUnitsName(lagMaximum, "Average")
+	assert.Equal(t, "aws_rds_aurora_replica_lag_maximum_average_seconds", name)
+	name, _ = baseUnitsName(lagMaximum, "Maximum")
+	assert.Equal(t, "aws_rds_aurora_replica_lag_maximum_maximum_seconds", name)
+}
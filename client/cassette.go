@@ -0,0 +1,284 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// Cassette file contains sanitized AWS API interactions, one JSON object per line.
+// It never contains request signatures or credentials: only selected headers are saved,
+// and secrets are redacted in both request and response bodies.
+
+// interaction represents a single recorded AWS API request and response.
+type interaction struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+	Target string `json:"target,omitempty"` // X-Amz-Target header value for JSON APIs like CloudWatch Logs
+	Body   string `json:"body,omitempty"`
+
+	StatusCode   int         `json:"status_code"`
+	Header       http.Header `json:"header,omitempty"`
+	ResponseBody string      `json:"response_body,omitempty"`
+}
+
+// key returns a string used to match replayed requests with recorded interactions.
+// Parameters that change on every request (like time ranges) are ignored.
+func (i *interaction) key() string {
+	return i.Method + " " + i.URL + " " + i.Target + " " + normalizeBody(i.Body)
+}
+
+// volatileParams contains request parameters ignored when matching requests.
+var volatileParams = map[string]struct{}{
+	"StartTime": {},
+	"EndTime":   {},
+	"startTime": {},
+	"endTime":   {},
+}
+
+// normalizeBody removes volatile parameters from form-encoded or JSON request body.
+func normalizeBody(body string) string {
+	if body == "" {
+		return ""
+	}
+
+	if strings.HasPrefix(body, "{") {
+		var m map[string]interface{}
+		if err := json.Unmarshal([]byte(body), &m); err != nil {
+			return body
+		}
+		for p := range volatileParams {
+			delete(m, p)
+		}
+		b, err := json.Marshal(m) // keys are sorted
+		if err != nil {
+			return body
+		}
+		return string(b)
+	}
+
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return body
+	}
+	for p := range volatileParams {
+		values.Del(p)
+	}
+	return values.Encode() // keys are sorted
+}
+
+// secretParams contains names of parameters and fields with credentials and other secrets.
+var secretParams = []string{"AccessKeyId", "SecretAccessKey", "SessionToken", "WebIdentityToken", "SAMLAssertion"}
+
+// secretRE matches credentials in XML and JSON bodies (for example, from STS AssumeRole).
+var secretRE = regexp.MustCompile(`(<(` + strings.Join(secretParams, "|") + `)>)[^<]*(</)|("(` + strings.Join(secretParams, "|") + `)"\s*:\s*")[^"]*(")`)
+
+// redactSecrets replaces credentials in given response or JSON request body.
+func redactSecrets(body string) string {
+	return secretRE.ReplaceAllString(body, "${1}${4}REDACTED${3}${6}")
+}
+
+// redactRequestBody replaces secrets in given form-encoded or JSON request body
+// (for example, WebIdentityToken for STS AssumeRoleWithWebIdentity).
+func redactRequestBody(body string) string {
+	if body == "" || strings.HasPrefix(body, "{") {
+		return redactSecrets(body)
+	}
+
+	values, err := url.ParseQuery(body)
+	if err != nil {
+		return body
+	}
+	var redacted bool
+	for _, p := range secretParams {
+		if _, ok := values[p]; ok {
+			values.Set(p, "REDACTED")
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+	return values.Encode()
+}
+
+// recordedHeaders contains response headers that are saved to cassette.
+var recordedHeaders = []string{"Content-Type", "X-Amzn-Requestid", "X-Amz-Request-Id"}
+
+// cassette records or replays AWS API interactions.
+type cassette struct {
+	rw sync.Mutex
+
+	// recording
+	w      *os.File
+	closed bool
+
+	// replaying
+	interactions map[string][]*interaction
+}
+
+// newRecordingCassette creates a new cassette file with given name for recording.
+func newRecordingCassette(filename string) (*cassette, error) {
+	f, err := os.Create(filename) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	return &cassette{w: f}, nil
+}
+
+// newReplayingCassette loads interactions from the cassette file with given name.
+func newReplayingCassette(filename string) (*cassette, error) {
+	f, err := os.Open(filename) //nolint:gosec
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close() //nolint:errcheck
+
+	c := &cassette{
+		interactions: make(map[string][]*interaction),
+	}
+	s := bufio.NewScanner(f)
+	s.Buffer(nil, 64*1024*1024)
+	for s.Scan() {
+		if len(bytes.TrimSpace(s.Bytes())) == 0 {
+			continue
+		}
+		var i interaction
+		if err = json.Unmarshal(s.Bytes(), &i); err != nil {
+			return nil, fmt.Errorf("%s: %s", filename, err)
+		}
+		k := i.key()
+		c.interactions[k] = append(c.interactions[k], &i)
+	}
+	if err = s.Err(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// close closes the recording cassette file; it does nothing for replaying cassette.
+func (c *cassette) close() error {
+	c.rw.Lock()
+	defer c.rw.Unlock()
+
+	if c.w == nil || c.closed {
+		return nil
+	}
+	c.closed = true
+	if err := c.w.Sync(); err != nil {
+		_ = c.w.Close()
+		return err
+	}
+	return c.w.Close()
+}
+
+// readRequest returns a new interaction filled with request fields (with redacted secrets),
+// and a request copy with the original body that can be read again.
+func readRequest(req *http.Request) (*interaction, *http.Request, error) {
+	i := &interaction{
+		Method: req.Method,
+		URL:    req.URL.String(),
+		Target: req.Header.Get("X-Amz-Target"),
+	}
+	if req.Body == nil || req.Body == http.NoBody {
+		return i, req, nil
+	}
+
+	b, err := io.ReadAll(req.Body)
+	_ = req.Body.Close()
+	if err != nil {
+		return nil, nil, err
+	}
+	i.Body = redactRequestBody(string(b))
+
+	req = req.Clone(req.Context())
+	req.Body = io.NopCloser(bytes.NewReader(b))
+	return i, req, nil
+}
+
+// replay returns recorded response for given request.
+// Interactions with the same key are returned in the recorded order; the last one is repeated.
+func (c *cassette) replay(req *http.Request) (*http.Response, error) {
+	i, req, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	k := i.key()
+	c.rw.Lock()
+	recorded := c.interactions[k]
+	if len(recorded) > 1 {
+		c.interactions[k] = recorded[1:]
+	}
+	c.rw.Unlock()
+	if len(recorded) == 0 {
+		return nil, fmt.Errorf("cassette: no recorded interaction for %s", k)
+	}
+
+	r := recorded[0]
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", r.StatusCode, http.StatusText(r.StatusCode)),
+		StatusCode:    r.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        r.Header.Clone(),
+		Body:          io.NopCloser(strings.NewReader(r.ResponseBody)),
+		ContentLength: int64(len(r.ResponseBody)),
+		Request:       req,
+	}, nil
+}
+
+// record performs request with given round tripper and saves sanitized interaction.
+func (c *cassette) record(rt http.RoundTripper, req *http.Request) (*http.Response, error) {
+	i, req, err := readRequest(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := rt.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	b, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+
+	i.StatusCode = resp.StatusCode
+	i.Header = make(http.Header)
+	for _, h := range recordedHeaders {
+		if v := resp.Header.Get(h); v != "" {
+			i.Header.Set(h, v)
+		}
+	}
+	i.ResponseBody = redactSecrets(string(b))
+
+	line, err := json.Marshal(i)
+	if err != nil {
+		return nil, err
+	}
+	c.rw.Lock()
+	if c.closed {
+		err = fmt.Errorf("cassette: recording is closed")
+	} else {
+		_, err = c.w.Write(append(line, '\n'))
+	}
+	c.rw.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return resp, nil
+}
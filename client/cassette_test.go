@@ -0,0 +1,112 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/common/promlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeBody(t *testing.T) {
+	assert.Equal(t,
+		"Action=GetMetricStatistics&MetricName=CPUUtilization",
+		normalizeBody("MetricName=CPUUtilization&StartTime=2020-12-06T10%3A20%3A00Z&Action=GetMetricStatistics&EndTime=2020-12-06T10%3A30%3A00Z"),
+	)
+	assert.Equal(t,
+		`{"logGroupName":"RDSOSMetrics","logStreamNames":["db-1"]}`,
+		normalizeBody(`{"logStreamNames":["db-1"],"logGroupName":"RDSOSMetrics","startTime":1607250834000}`),
+	)
+}
+
+func TestRedactSecrets(t *testing.T) {
+	assert.Equal(t,
+		"<Credentials><AccessKeyId>REDACTED</AccessKeyId><SecretAccessKey>REDACTED</SecretAccessKey><Expiration>2020</Expiration></Credentials>",
+		redactSecrets("<Credentials><AccessKeyId>ASIA</AccessKeyId><SecretAccessKey>secret</SecretAccessKey><Expiration>2020</Expiration></Credentials>"),
+	)
+	assert.Equal(t,
+		`{"SessionToken": "REDACTED","Expiration":2020}`,
+		redactSecrets(`{"SessionToken": "token","Expiration":2020}`),
+	)
+}
+
+func TestRedactRequestBody(t *testing.T) {
+	assert.Equal(t,
+		"Action=AssumeRoleWithWebIdentity&RoleArn=arn&WebIdentityToken=REDACTED",
+		redactRequestBody("Action=AssumeRoleWithWebIdentity&RoleArn=arn&WebIdentityToken=eyJhbGciOi"),
+	)
+	assert.Equal(t,
+		"Action=AssumeRoleWithSAML&SAMLAssertion=REDACTED",
+		redactRequestBody("SAMLAssertion=PHNhbWw%3D&Action=AssumeRoleWithSAML"),
+	)
+	assert.Equal(t,
+		"Action=GetMetricData&StartTime=1",
+		redactRequestBody("Action=GetMetricData&StartTime=1"),
+		"bodies without secrets should not be changed",
+	)
+	assert.Equal(t,
+		`{"WebIdentityToken":"REDACTED"}`,
+		redactRequestBody(`{"WebIdentityToken":"eyJhbGciOi"}`),
+	)
+}
+
+func TestCassette(t *testing.T) {
+	var requests int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		b, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		w.Header().Set("X-Amzn-Requestid", "request-id")
+		w.Header().Set("Date", "Sun, 06 Dec 2020 10:33:54 GMT")
+		_, _ = w.Write([]byte("<SecretAccessKey>secret</SecretAccessKey>" + string(b)))
+	}))
+	defer srv.Close()
+
+	filename := filepath.Join(t.TempDir(), "test.jsonl")
+	logger := promlog.New(&promlog.Config{})
+
+	do := func(c *Client, body string) string {
+		resp, err := c.HTTP().Post(srv.URL, "application/x-www-form-urlencoded", strings.NewReader(body))
+		require.NoError(t, err)
+		defer resp.Body.Close() //nolint:errcheck
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		b, err := io.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return string(b)
+	}
+
+	c := New(logger)
+	c.t.t.Proxy = nil
+	require.NoError(t, c.Record(filename))
+	assert.Equal(t, "<SecretAccessKey>secret</SecretAccessKey>Action=A&StartTime=1", do(c, "Action=A&StartTime=1"))
+	assert.Equal(t, "<SecretAccessKey>secret</SecretAccessKey>Action=B", do(c, "Action=B"))
+	assert.Equal(t, "<SecretAccessKey>secret</SecretAccessKey>Action=W&WebIdentityToken=token", do(c, "Action=W&WebIdentityToken=token"))
+	assert.Equal(t, 3, requests)
+	require.NoError(t, c.Close())
+	require.NoError(t, c.Close())
+	_, err := c.HTTP().Post(srv.URL, "application/x-www-form-urlencoded", strings.NewReader("Action=C"))
+	assert.Error(t, err, "requests should fail after the cassette is closed")
+
+	b, err := os.ReadFile(filename) //nolint:gosec
+	require.NoError(t, err)
+	assert.NotContains(t, string(b), "secret")
+	assert.Contains(t, string(b), `"body":"Action=W\u0026WebIdentityToken=REDACTED"`)
+	assert.NotContains(t, string(b), "Date")
+
+	c = New(logger)
+	require.NoError(t, c.Replay(filename))
+	assert.Equal(t, "<SecretAccessKey>REDACTED</SecretAccessKey>Action=B", do(c, "Action=B"))
+	assert.Equal(t, "<SecretAccessKey>REDACTED</SecretAccessKey>Action=A&StartTime=1", do(c, "StartTime=2&Action=A"))
+	assert.Equal(t, "<SecretAccessKey>REDACTED</SecretAccessKey>Action=A&StartTime=1", do(c, "Action=A"))
+	assert.Equal(t, "<SecretAccessKey>REDACTED</SecretAccessKey>Action=W&WebIdentityToken=token", do(c, "Action=W&WebIdentityToken=other"))
+	assert.Equal(t, 4, requests)
+
+	_, err = c.HTTP().Post(srv.URL, "application/x-www-form-urlencoded", strings.NewReader("Action=C"))
+	assert.Error(t, err)
+}
@@ -31,14 +31,49 @@ func (c *Client) HTTP() *http.Client {
 	return c.c
 }
 
+// Record makes client save sanitized AWS API requests and responses to the cassette file with given name.
+// It should be called before the client is used.
+func (c *Client) Record(filename string) error {
+	cassette, err := newRecordingCassette(filename)
+	if err != nil {
+		return err
+	}
+	c.t.c = cassette
+	return nil
+}
+
+// Replay makes client serve AWS API responses from the cassette file with given name
+// instead of using network. It should be called before the client is used.
+func (c *Client) Replay(filename string) error {
+	cassette, err := newReplayingCassette(filename)
+	if err != nil {
+		return err
+	}
+	c.t.c = cassette
+	return nil
+}
+
+// Close flushes and closes the cassette file if the client records interactions.
+// Requests made after that are not recorded and fail.
+func (c *Client) Close() error {
+	if c.t.c == nil {
+		return nil
+	}
+	return c.t.c.close()
+}
+
 // Describe implements prometheus.Collector.
 func (c *Client) Describe(ch chan<- *prometheus.Desc) {
 	c.t.mRequests.Describe(ch)
+	c.t.mPhases.Describe(ch)
+	c.t.mConnections.Describe(ch)
 }
 
 // Collect implements prometheus.Collector.
 func (c *Client) Collect(ch chan<- prometheus.Metric) {
 	c.t.mRequests.Collect(ch)
+	c.t.mPhases.Collect(ch)
+	c.t.mConnections.Collect(ch)
 }
 
 // check interfaces
@@ -0,0 +1,52 @@
+// Package clienttest provides AWS API client for tests that record and replay interactions.
+package clienttest
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/client"
+)
+
+var (
+	record = flag.Bool("record", false, "record AWS API interactions to testdata/cassettes")
+	replay = flag.Bool("replay", false, "replay AWS API interactions from testdata/cassettes")
+)
+
+// New returns AWS API client that records interactions to the test's cassette file
+// if -record flag is set, or replays them if -replay flag is set.
+// In replay mode, tests without recorded cassette are skipped.
+// Cassette file is closed when the test ends.
+func New(t *testing.T, logger log.Logger) *client.Client {
+	t.Helper()
+
+	c := client.New(logger)
+	filename := filepath.Join("testdata", "cassettes", t.Name()+".jsonl")
+	switch {
+	case *record:
+		require.NoError(t, os.MkdirAll(filepath.Dir(filename), 0777))
+		require.NoError(t, c.Record(filename))
+	case *replay:
+		if _, err := os.Stat(filename); os.IsNotExist(err) {
+			t.Skipf("%s is not recorded yet; run tests with -record flag.", filename)
+		}
+		require.NoError(t, c.Replay(filename))
+
+		// requests are still signed, so any credentials will do
+		for _, k := range []string{"AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_ACCESS_KEY", "AWS_SECRET_KEY"} {
+			if os.Getenv(k) == "" {
+				t.Setenv(k, "replay")
+			}
+		}
+	}
+
+	t.Cleanup(func() {
+		require.NoError(t, c.Close())
+	})
+	return c
+}
@@ -1,9 +1,14 @@
 package client
 
 import (
+	"crypto/tls"
 	"fmt"
+	"net"
 	"net/http"
+	"net/http/httptrace"
 	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/go-kit/log"
@@ -14,9 +19,12 @@ import (
 type transport struct {
 	t *http.Transport
 	l log.Logger
+	c *cassette // may be nil
 
-	mRequests  prometheus.Counter
-	mResponses *prometheus.SummaryVec
+	mRequests    prometheus.Counter
+	mResponses   *prometheus.SummaryVec
+	mPhases      *prometheus.HistogramVec
+	mConnections *prometheus.CounterVec
 }
 
 func newTransport(logger log.Logger) *transport {
@@ -36,16 +44,126 @@ func newTransport(logger log.Logger) *transport {
 			Name: "rds_exporter_responses_durations_seconds",
 			Help: "AWS API responses latency distributions.",
 		}, []string{"status"}),
+		mPhases: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "rds_exporter_request_phase_duration_seconds",
+			Help:    "AWS API requests latency distributions by phase: dns, connect, proxy, tls, and server.",
+			Buckets: []float64{.001, .0025, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+		}, []string{"service", "region", "phase"}),
+		mConnections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_connections_total",
+			Help: "Total number of connections used for AWS API requests by reuse status.",
+		}, []string{"service", "region", "reused"}),
+	}
+}
+
+// endpoint returns AWS service and region for given API host name
+// like "monitoring.us-east-1.amazonaws.com" or "sts.amazonaws.com".
+func endpoint(host string) (service, region string) {
+	// host may be without port, and IPv6 addresses contain colons
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+
+	parts := strings.Split(host, ".")
+	if len(parts) < 3 || net.ParseIP(host) != nil {
+		return host, ""
+	}
+	service = parts[0]
+	if len(parts) > 3 {
+		region = parts[1]
+	}
+	return service, region
+}
+
+// phaseTimes contains request phases start and end times collected by httptrace hooks.
+type phaseTimes struct {
+	rw sync.Mutex
+
+	dnsStart, dnsDone           time.Time
+	connectStart, connectDone   time.Time
+	tlsStart, tlsDone           time.Time
+	wroteRequest, firstResponse time.Time
+	reused                      bool
+	gotConn                     bool
+}
+
+// clientTrace returns httptrace hooks that fill phase times.
+func (p *phaseTimes) clientTrace() *httptrace.ClientTrace {
+	set := func(t *time.Time) {
+		p.rw.Lock()
+		*t = time.Now()
+		p.rw.Unlock()
+	}
+
+	return &httptrace.ClientTrace{
+		DNSStart:          func(httptrace.DNSStartInfo) { set(&p.dnsStart) },
+		DNSDone:           func(httptrace.DNSDoneInfo) { set(&p.dnsDone) },
+		ConnectStart:      func(string, string) { set(&p.connectStart) },
+		ConnectDone:       func(string, string, error) { set(&p.connectDone) },
+		TLSHandshakeStart: func() { set(&p.tlsStart) },
+		TLSHandshakeDone:  func(tls.ConnectionState, error) { set(&p.tlsDone) },
+		GotConn: func(info httptrace.GotConnInfo) {
+			p.rw.Lock()
+			p.gotConn = true
+			p.reused = info.Reused
+			p.rw.Unlock()
+		},
+		WroteRequest:         func(httptrace.WroteRequestInfo) { set(&p.wroteRequest) },
+		GotFirstResponseByte: func() { set(&p.firstResponse) },
+	}
+}
+
+// observe reports collected phase durations.
+func (t *transport) observe(p *phaseTimes, service, region string, proxied bool) {
+	p.rw.Lock()
+	defer p.rw.Unlock()
+
+	observe := func(phase string, start, end time.Time) {
+		if start.IsZero() || end.IsZero() {
+			return
+		}
+		t.mPhases.WithLabelValues(service, region, phase).Observe(end.Sub(start).Seconds())
+	}
+	observe("dns", p.dnsStart, p.dnsDone)
+	observe("connect", p.connectStart, p.connectDone)
+	if proxied {
+		// time between connection to proxy and TLS handshake with AWS is spent on CONNECT request
+		observe("proxy", p.connectDone, p.tlsStart)
+	}
+	observe("tls", p.tlsStart, p.tlsDone)
+	observe("server", p.wroteRequest, p.firstResponse)
+
+	if p.gotConn {
+		t.mConnections.WithLabelValues(service, region, strconv.FormatBool(p.reused)).Inc()
 	}
 }
 
 func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
-	// We could use "net/http/httptrace" package if we ever need more metrics.
+	if t.c != nil && t.c.w == nil {
+		return t.c.replay(req)
+	}
+
+	var proxied bool
+	if t.t.Proxy != nil {
+		if u, _ := t.t.Proxy(req); u != nil {
+			proxied = true
+		}
+	}
+	service, region := endpoint(req.URL.Host)
+	p := new(phaseTimes)
+	req = req.WithContext(httptrace.WithClientTrace(req.Context(), p.clientTrace()))
 
 	start := time.Now()
 	t.mRequests.Inc()
-	resp, err := t.t.RoundTrip(req)
+	var resp *http.Response
+	var err error
+	if t.c != nil {
+		resp, err = t.c.record(t.t, req)
+	} else {
+		resp, err = t.t.RoundTrip(req)
+	}
 	duration := time.Since(start)
+	t.observe(p, service, region, proxied)
 	if resp != nil {
 		t.mResponses.WithLabelValues(strconv.Itoa(resp.StatusCode)).Observe(duration.Seconds())
 		level.Debug(t.l).Log("msg", fmt.Sprintf("%s %s -> %d (%s)", req.Method, req.URL.String(), resp.StatusCode, duration))
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/promlog"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEndpoint(t *testing.T) {
+	for host, expected := range map[string][2]string{
+		"monitoring.us-east-1.amazonaws.com": {"monitoring", "us-east-1"},
+		"logs.eu-west-1.amazonaws.com:443":   {"logs", "eu-west-1"},
+		"rds.cn-north-1.amazonaws.com.cn":    {"rds", "cn-north-1"},
+		"sts.amazonaws.com":                  {"sts", ""},
+		"127.0.0.1:8080":                     {"127.0.0.1", ""},
+		"[::1]:443":                          {"::1", ""},
+		"::1":                                {"::1", ""},
+		"localhost":                          {"localhost", ""},
+	} {
+		service, region := endpoint(host)
+		assert.Equal(t, expected, [2]string{service, region}, "%s", host)
+	}
+}
+
+func TestTransportTrace(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	c := New(promlog.New(&promlog.Config{}))
+	c.t.t.Proxy = nil
+	for i := 0; i < 2; i++ {
+		resp, err := c.HTTP().Get(srv.URL)
+		require.NoError(t, err)
+		require.NoError(t, resp.Body.Close())
+	}
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.t.mConnections.WithLabelValues("127.0.0.1", "", "false")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(c.t.mConnections.WithLabelValues("127.0.0.1", "", "true")))
+	assert.Equal(t, 2, testutil.CollectAndCount(c.t.mPhases)) // connect and server
+}
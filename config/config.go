@@ -1,7 +1,10 @@
 package config
 
 import (
+	"fmt"
 	"io/ioutil"
+	"regexp"
+	"time"
 
 	"gopkg.in/yaml.v2"
 )
@@ -26,6 +29,13 @@ type Instance struct {
 	DisableBasicMetrics    bool              `yaml:"disable_basic_metrics"`
 	DisableEnhancedMetrics bool              `yaml:"disable_enhanced_metrics"`
 	Labels                 map[string]string `yaml:"labels"` // may be empty
+	Trace                  bool              `yaml:"trace"`  // enables AWS requests tracing for instance's session
+
+	// override Basic settings; may be empty
+	BasicPeriod   time.Duration `yaml:"basic_period"`
+	BasicDelay    time.Duration `yaml:"basic_delay"`
+	BasicRange    time.Duration `yaml:"basic_range"`
+	BasicAdaptive *bool         `yaml:"basic_adaptive"`
 
 	// TODO Type InstanceType `yaml:"type"` // may be empty for old pmm-managed
 }
@@ -39,9 +49,177 @@ func (i Instance) String() string {
 	return res
 }
 
+// Basic contains basic metrics configuration.
+type Basic struct {
+	// CloudWatch metric name -> statistics, for example, "ReadLatency": ["Average", "Maximum", "p99"].
+	// Only "Average" is used for metrics not present there.
+	Statistics map[string][]string `yaml:"statistics"`
+
+	// CloudWatch datapoints period, and delay and range of requested time interval; may be empty for defaults.
+	Period time.Duration `yaml:"period"`
+	Delay  time.Duration `yaml:"delay"`
+	Range  time.Duration `yaml:"range"`
+
+	// Adaptive makes requested time interval end now (delay is ignored),
+	// and the newest complete period used.
+	Adaptive bool `yaml:"adaptive"`
+
+	// Timestamps makes metrics have datapoints timestamps.
+	Timestamps bool `yaml:"timestamps"`
+
+	// DatapointAge enables aws_rds_datapoint_age_seconds metric.
+	DatapointAge bool `yaml:"datapoint_age"`
+
+	// BaseUnits makes metrics names and values use Prometheus base units, for example, aws_rds_read_latency_seconds.
+	BaseUnits bool `yaml:"base_units"`
+
+	// Fleet enables metrics aggregated by DatabaseClass and EngineName dimensions for every region and account.
+	Fleet bool `yaml:"fleet"`
+
+	// Discover makes metrics for every instance discovered with ListMetrics instead of using a fixed list.
+	// Discovery is repeated with DiscoverInterval; it may be empty for default.
+	Discover         bool          `yaml:"discover"`
+	DiscoverInterval time.Duration `yaml:"discover_interval"`
+
+	// Interval enables background polling with given interval; metrics are scraped on every request if empty.
+	Interval time.Duration `yaml:"interval"`
+}
+
+// Enhanced contains enhanced metrics configuration.
+type Enhanced struct {
+	// UnlimitedVMLimit defines how "unlimited" processList vmlimit value is exposed:
+	// "omit" (or empty) skips the metric, "inf" makes it +Inf.
+	UnlimitedVMLimit string `yaml:"unlimited_vmlimit"`
+
+	// ProcessList defines how processList metrics are exposed:
+	// "full" (or empty) for every process, "aggregate" for groups of processes with the same name or category.
+	ProcessList string `yaml:"process_list"`
+
+	// ProcessListTop enables metrics for the given number of processes using the most CPU; 0 disables them.
+	ProcessListTop int `yaml:"process_list_top"`
+
+	// SynthesizeCounters enables node_exporter-like counters synthesized from rates of successive events.
+	SynthesizeCounters bool `yaml:"synthesize_counters"`
+
+	// TTL defines how long instance's metrics are exposed after the latest event; 0 exposes them forever.
+	TTL time.Duration `yaml:"ttl"`
+
+	// Timestamps defines which timestamps are set on exposed metrics:
+	// "none" (or empty) for the scrape time, "event" for CloudWatch Logs event timestamp,
+	// "message" for the timestamp from enhanced monitoring message.
+	Timestamps string `yaml:"timestamps"`
+
+	// Buffer enables exposing all events received during that window instead of only the latest one; 0 disables it.
+	Buffer time.Duration `yaml:"buffer"`
+
+	WindowStats WindowStats `yaml:"window_stats"`
+}
+
+// WindowStats contains configuration of statistics over enhanced monitoring events received since the last scrape.
+type WindowStats struct {
+	// Metrics contains name prefixes of gauge metrics for statistics; empty disables them.
+	Metrics []string `yaml:"metrics"`
+
+	// Quantiles contains quantiles exposed in addition to minimum, maximum, and mean, like 0.95.
+	Quantiles []float64 `yaml:"quantiles"`
+}
+
+// MaxProcessListTop is the maximal enhanced.process_list_top value.
+const MaxProcessListTop = 100
+
 // Config contains configuration file information.
 type Config struct {
 	Instances []Instance `yaml:"instances"`
+	Basic     Basic      `yaml:"basic"`
+	Enhanced  Enhanced   `yaml:"enhanced"`
+}
+
+// statisticRE matches CloudWatch statistics and extended statistics like p99 or p99.9.
+var statisticRE = regexp.MustCompile(`^(SampleCount|Average|Sum|Minimum|Maximum|p\d{1,2}(\.\d{1,2})?|p100)$`)
+
+// validPeriod returns true if given CloudWatch datapoints period is accepted by GetMetricData, or is empty for default.
+// https://docs.aws.amazon.com/AmazonCloudWatch/latest/APIReference/API_MetricStat.html
+func validPeriod(d time.Duration) bool {
+	switch d {
+	case 0, time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second:
+		return true
+	default:
+		return d > 0 && d%time.Minute == 0
+	}
+}
+
+// validate checks configuration.
+func (c *Config) validate() error {
+	durations := map[string]time.Duration{
+		"basic.period":            c.Basic.Period,
+		"basic.delay":             c.Basic.Delay,
+		"basic.range":             c.Basic.Range,
+		"basic.interval":          c.Basic.Interval,
+		"basic.discover_interval": c.Basic.DiscoverInterval,
+		"enhanced.ttl":            c.Enhanced.TTL,
+		"enhanced.buffer":         c.Enhanced.Buffer,
+	}
+	for _, instance := range c.Instances {
+		durations[instance.String()+" basic_period"] = instance.BasicPeriod
+		durations[instance.String()+" basic_delay"] = instance.BasicDelay
+		durations[instance.String()+" basic_range"] = instance.BasicRange
+	}
+	for name, d := range durations {
+		if d < 0 || d%time.Second != 0 {
+			return fmt.Errorf("invalid %s %s: should be a non-negative whole number of seconds", name, d)
+		}
+	}
+
+	periods := map[string]time.Duration{
+		"basic.period": c.Basic.Period,
+	}
+	for _, instance := range c.Instances {
+		periods[instance.String()+" basic_period"] = instance.BasicPeriod
+	}
+	for name, d := range periods {
+		if !validPeriod(d) {
+			return fmt.Errorf("invalid %s %s: should be 1s, 5s, 10s, 30s, or a multiple of 60s", name, d)
+		}
+	}
+
+	switch c.Enhanced.UnlimitedVMLimit {
+	case "", "omit", "inf":
+		// nothing
+	default:
+		return fmt.Errorf("invalid enhanced.unlimited_vmlimit %q: should be \"omit\" or \"inf\"", c.Enhanced.UnlimitedVMLimit)
+	}
+
+	switch c.Enhanced.ProcessList {
+	case "", "full", "aggregate":
+		// nothing
+	default:
+		return fmt.Errorf("invalid enhanced.process_list %q: should be \"full\" or \"aggregate\"", c.Enhanced.ProcessList)
+	}
+	if c.Enhanced.ProcessListTop < 0 || c.Enhanced.ProcessListTop > MaxProcessListTop {
+		return fmt.Errorf("invalid enhanced.process_list_top %d: should be between 0 and %d", c.Enhanced.ProcessListTop, MaxProcessListTop)
+	}
+
+	switch c.Enhanced.Timestamps {
+	case "", "none", "event", "message":
+		// nothing
+	default:
+		return fmt.Errorf("invalid enhanced.timestamps %q: should be \"none\", \"event\", or \"message\"", c.Enhanced.Timestamps)
+	}
+
+	for _, q := range c.Enhanced.WindowStats.Quantiles {
+		if q <= 0 || q >= 1 {
+			return fmt.Errorf("invalid enhanced.window_stats quantile %v: should be between 0 and 1", q)
+		}
+	}
+
+	for metric, statistics := range c.Basic.Statistics {
+		for _, s := range statistics {
+			if !statisticRE.MatchString(s) {
+				return fmt.Errorf("invalid statistic %q for metric %s", s, metric)
+			}
+		}
+	}
+	return nil
 }
 
 // Load loads configuration from file.
@@ -55,6 +233,9 @@ func Load(filename string) (*Config, error) {
 	if err = yaml.Unmarshal(b, &config); err != nil {
 		return nil, err
 	}
+	if err = config.validate(); err != nil {
+		return nil, err
+	}
 
 	return &config, nil
 }
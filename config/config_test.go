@@ -0,0 +1,23 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidatePeriod(t *testing.T) {
+	for _, d := range []time.Duration{0, time.Second, 5 * time.Second, 10 * time.Second, 30 * time.Second, time.Minute, 5 * time.Minute} {
+		c := &Config{Basic: Basic{Period: d}}
+		assert.NoError(t, c.validate(), "%s", d)
+	}
+
+	for _, d := range []time.Duration{2 * time.Second, 15 * time.Second, 90 * time.Second} {
+		c := &Config{Basic: Basic{Period: d}}
+		assert.EqualError(t, c.validate(), "invalid basic.period "+d.String()+": should be 1s, 5s, 10s, 30s, or a multiple of 60s")
+
+		c = &Config{Instances: []Instance{{Region: "us-east-1", Instance: "rds-mysql57", BasicPeriod: d}}}
+		assert.Error(t, c.validate(), "%s", d)
+	}
+}
@@ -0,0 +1,141 @@
+package enhanced
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// event contains metrics of a single enhanced monitoring event.
+type event struct {
+	time    time.Time
+	metrics []prometheus.Metric // with explicit timestamps
+}
+
+// addEvents appends new events to buffered ones, and removes events older than window.
+// The latest event is always kept.
+func addEvents(buffered, events []event, window time.Duration, now time.Time) []event {
+	for _, e := range events {
+		// the latest event is returned by FilterLogEvents again
+		if len(buffered) == 0 || e.time.After(buffered[len(buffered)-1].time) {
+			buffered = append(buffered, e)
+		}
+	}
+
+	return windowEvents(buffered, window, now)
+}
+
+// windowEvents returns events that are not older than window; the latest event is always returned.
+func windowEvents(events []event, window time.Duration, now time.Time) []event {
+	cutoff := now.Add(-window)
+	for i, e := range events {
+		if !e.time.Before(cutoff) || i == len(events)-1 {
+			return events[i:]
+		}
+	}
+	return events
+}
+
+// metricsCollector is an unchecked collector for a fixed set of metrics.
+type metricsCollector []prometheus.Metric
+
+// Describe implements prometheus.Collector.
+func (metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	// unchecked collector
+}
+
+// Collect implements prometheus.Collector.
+func (mc metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	for _, m := range mc {
+		ch <- m
+	}
+}
+
+// Gatherer returns a Gatherer for the given registry with that collector.
+// In buffered mode, it adds all buffered events to metrics gathered from that registry.
+// Every gather exposes the whole buffer window; Prometheus de-duplicates samples by their timestamps.
+func (c *Collector) Gatherer(registry prometheus.Gatherer) prometheus.Gatherer {
+	if c.config.Buffer == 0 {
+		return registry
+	}
+
+	return prometheus.GathererFunc(func() ([]*dto.MetricFamily, error) {
+		mfs, err := registry.Gather()
+		if err != nil {
+			return mfs, err
+		}
+
+		// Registry does not allow the same metric to be collected several times,
+		// even with different timestamps, so each event of instance is gathered by a separate registry.
+		var layers []metricsCollector
+		c.rw.RLock()
+		now := time.Now()
+		for id, events := range c.events {
+			if c.expired(now.Sub(c.times[id])) {
+				continue
+			}
+			for i, e := range windowEvents(events, c.config.Buffer, now) {
+				if i == len(layers) {
+					layers = append(layers, nil)
+				}
+				layers[i] = append(layers[i], e.metrics...)
+			}
+		}
+		c.rw.RUnlock()
+
+		for _, layer := range layers {
+			r := prometheus.NewRegistry()
+			if err = r.Register(layer); err != nil {
+				return mfs, err
+			}
+			layerMFs, err := r.Gather()
+			if err != nil {
+				return mfs, err
+			}
+			mfs = mergeMetricFamilies(mfs, layerMFs)
+		}
+		return mfs, nil
+	})
+}
+
+// mergeMetricFamilies merges metric families without checking for duplicate metrics,
+// and sorts them by name, and metrics by labels and timestamp.
+func mergeMetricFamilies(mfs, other []*dto.MetricFamily) []*dto.MetricFamily {
+	byName := make(map[string]*dto.MetricFamily, len(mfs))
+	for _, mf := range mfs {
+		byName[mf.GetName()] = mf
+	}
+	for _, mf := range other {
+		if existing := byName[mf.GetName()]; existing != nil {
+			existing.Metric = append(existing.Metric, mf.Metric...)
+			continue
+		}
+		byName[mf.GetName()] = mf
+		mfs = append(mfs, mf)
+	}
+
+	sort.Slice(mfs, func(i, j int) bool { return mfs[i].GetName() < mfs[j].GetName() })
+	for _, mf := range mfs {
+		metrics := mf.Metric
+		sort.SliceStable(metrics, func(i, j int) bool {
+			li, lj := labelsKey(metrics[i]), labelsKey(metrics[j])
+			if li != lj {
+				return li < lj
+			}
+			return metrics[i].GetTimestampMs() < metrics[j].GetTimestampMs()
+		})
+	}
+	return mfs
+}
+
+// labelsKey returns a string representation of metric's labels for sorting.
+func labelsKey(m *dto.Metric) string {
+	pairs := make([]string, len(m.Label))
+	for i, l := range m.Label {
+		pairs[i] = l.GetName() + "\xff" + l.GetValue()
+	}
+	return strings.Join(pairs, "\xfe")
+}
@@ -0,0 +1,111 @@
+package enhanced
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/status"
+)
+
+func TestAddEvents(t *testing.T) {
+	now := time.Date(2021, 1, 1, 0, 1, 0, 0, time.UTC)
+	at := func(seconds int) event {
+		return event{time: now.Add(time.Duration(seconds) * time.Second)}
+	}
+	times := func(events []event) []int {
+		res := make([]int, len(events))
+		for i, e := range events {
+			res[i] = int(e.time.Sub(now) / time.Second)
+		}
+		return res
+	}
+
+	buffered := addEvents(nil, []event{at(-20), at(-10), at(-5)}, 15*time.Second, now)
+	assert.Equal(t, []int{-10, -5}, times(buffered))
+
+	buffered = addEvents(buffered, []event{at(-5), at(0)}, 15*time.Second, now)
+	assert.Equal(t, []int{-10, -5, 0}, times(buffered), "duplicate event should be skipped")
+
+	buffered = addEvents(buffered, nil, 15*time.Second, now.Add(time.Hour))
+	assert.Equal(t, []int{0}, times(buffered), "the latest event should be kept")
+}
+
+func TestCollectorGatherer(t *testing.T) {
+	now := time.Now().Truncate(time.Second)
+	makeEvent := func(instance string, ts time.Time, v float64) event {
+		desc := prometheus.NewDesc("rdsosmetrics_cpuUtilization_total", "The total percentage of the CPU in use.", nil, prometheus.Labels{"instance": instance})
+		m := prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, v)
+		return event{time: ts, metrics: []prometheus.Metric{prometheus.NewMetricWithTimestamp(ts, m)}}
+	}
+	var events []event
+	for i := 3; i > 0; i-- {
+		events = append(events, makeEvent("db", now.Add(-time.Duration(i)*time.Second), float64(i)))
+	}
+	other := makeEvent("db2", now.Add(-2*time.Second), 20)
+
+	c := &Collector{
+		config:  config.Enhanced{Buffer: time.Minute},
+		status:  status.New("enhanced"),
+		metrics: make(map[string][]prometheus.Metric),
+		times:   make(map[string]time.Time),
+		labels:  make(map[string]prometheus.Labels),
+		events:  make(map[string][]event),
+	}
+	c.setMetrics(&scrapeResult{
+		metrics: map[string][]prometheus.Metric{"db": events[2].metrics, "db2": other.metrics},
+		times:   map[string]time.Time{"db": events[2].time, "db2": other.time},
+		labels:  map[string]prometheus.Labels{"db": {"instance": "db"}, "db2": {"instance": "db2"}},
+		events:  map[string][]event{"db": events, "db2": {other}},
+	})
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(c)
+	gatherer := c.Gatherer(registry)
+
+	// gather returns "instance timestamp value" samples of buffered metric, and the number of collected age metrics
+	gather := func() ([]string, int) {
+		mfs, err := gatherer.Gather()
+		require.NoError(t, err)
+
+		var samples []string
+		var ages int
+		for _, mf := range mfs {
+			switch mf.GetName() {
+			case "rdsosmetrics_cpuUtilization_total":
+				for _, m := range mf.Metric {
+					ts := time.UnixMilli(m.GetTimestampMs())
+					samples = append(samples, fmt.Sprintf("%s %s %v", m.Label[0].GetValue(), now.Sub(ts), m.GetGauge().GetValue()))
+				}
+			case "rdsosmetrics_last_event_age_seconds":
+				ages = len(mf.Metric)
+			}
+		}
+		return samples, ages
+	}
+
+	// events of all instances are merged with metrics from the registry, and sorted by labels and timestamps
+	samples, ages := gather()
+	assert.Equal(t, []string{"db 3s 3", "db 2s 2", "db 1s 1", "db2 2s 20"}, samples)
+	assert.Equal(t, 2, ages)
+
+	// the whole buffer window is exposed again, so several scrapers and failed scrapes do not lose events
+	samples, ages = gather()
+	assert.Equal(t, []string{"db 3s 3", "db 2s 2", "db 1s 1", "db2 2s 20"}, samples)
+	assert.Equal(t, 2, ages)
+
+	// new events are added to buffered ones
+	c.setMetrics(&scrapeResult{
+		metrics: map[string][]prometheus.Metric{"db": events[2].metrics},
+		times:   map[string]time.Time{"db": now},
+		labels:  map[string]prometheus.Labels{"db": {"instance": "db"}},
+		events:  map[string][]event{"db": {events[2], makeEvent("db", now, 0)}},
+	})
+	samples, _ = gather()
+	assert.Equal(t, []string{"db 3s 3", "db 2s 2", "db 1s 1", "db 0s 0", "db2 2s 20"}, samples)
+}
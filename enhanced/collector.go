@@ -3,6 +3,7 @@ package enhanced
 import (
 	"context"
 	"fmt"
+	"sort"
 	"sync"
 	"time"
 
@@ -10,18 +11,30 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/percona/rds_exporter/config"
 	"github.com/percona/rds_exporter/sessions"
+	"github.com/percona/rds_exporter/status"
 )
 
 // Collector collects enhanced RDS metrics by utilizing several scrapers.
 type Collector struct {
 	sessions *sessions.Sessions
+	config   config.Enhanced
+	status   *status.Tracker
 	logger   log.Logger
 
 	rw      sync.RWMutex
-	metrics map[string][]prometheus.Metric
+	metrics map[string][]prometheus.Metric // ResourceID -> metrics of the latest event
+	times   map[string]time.Time           // ResourceID -> timestamp of the latest event
+	labels  map[string]prometheus.Labels   // ResourceID -> constant labels of instance's metrics
+	events  map[string][]event             // ResourceID -> buffered events, for buffered mode only
+	windows map[string]*window             // ResourceID -> window statistics
 }
 
+// removeAfter is the number of TTLs after which expired instances are removed completely,
+// so rdsosmetrics_last_event_age_seconds of deleted instances is not exposed forever.
+const removeAfter = 10
+
 // Maximal and minimal metrics update interval.
 const (
 	maxInterval = 60 * time.Second
@@ -29,42 +42,69 @@ const (
 )
 
 // NewCollector creates new collector and starts scrapers.
-func NewCollector(sessions *sessions.Sessions, logger log.Logger) *Collector {
+func NewCollector(sessions *sessions.Sessions, cfg config.Enhanced, logger log.Logger) *Collector {
 	c := &Collector{
 		sessions: sessions,
+		config:   cfg,
+		status:   status.New("enhanced"),
 		logger:   log.With(logger, "component", "enhanced"),
 		metrics:  make(map[string][]prometheus.Metric),
+		times:    make(map[string]time.Time),
+		labels:   make(map[string]prometheus.Labels),
+		events:   make(map[string][]event),
+		windows:  make(map[string]*window),
 	}
 
 	for session, instances := range sessions.AllSessions() {
-		enabledInstances := getEnabledInstances(instances)
-		s := newScraper(session, enabledInstances, logger)
-
-		interval := maxInterval
-		for _, instance := range enabledInstances {
-			if instance.EnhancedMonitoringInterval > 0 && instance.EnhancedMonitoringInterval < interval {
-				interval = instance.EnhancedMonitoringInterval
-			}
+		// instances with different intervals are scraped separately,
+		// so a single instance with short interval does not make others scraped too often
+		classes := intervalClasses(getEnabledInstances(instances))
+		intervals := make([]time.Duration, 0, len(classes))
+		for interval := range classes {
+			intervals = append(intervals, interval)
 		}
-		if interval < minInterval {
-			interval = minInterval
+		sort.Slice(intervals, func(i, j int) bool { return intervals[i] < intervals[j] })
+
+		for _, interval := range intervals {
+			s := newScraper(session, classes[interval], cfg, c.status, logger)
+			level.Info(s.logger).Log("msg", fmt.Sprintf("Updating enhanced metrics for %v every %s.", classes[interval], interval))
+
+			// perform first scrapes synchronously so returned collector has all metric descriptions
+			c.setMetrics(s.scrape(context.TODO()))
+
+			ch := make(chan *scrapeResult)
+			go func() {
+				for res := range ch {
+					c.setMetrics(res)
+				}
+			}()
+			go s.start(context.TODO(), interval, ch)
 		}
-		level.Info(s.logger).Log("msg", fmt.Sprintf("Updating enhanced metrics every %s.", interval))
+	}
 
-		// perform first scrapes synchronously so returned collector has all metric descriptions
-		m, _ := s.scrape(context.TODO())
-		c.setMetrics(m)
+	return c
+}
 
-		ch := make(chan map[string][]prometheus.Metric)
-		go func() {
-			for m := range ch {
-				c.setMetrics(m)
-			}
-		}()
-		go s.start(context.TODO(), interval, ch)
+// scrapeInterval returns metrics update interval for the instance.
+func scrapeInterval(instance sessions.Instance) time.Duration {
+	interval := instance.EnhancedMonitoringInterval
+	if interval <= 0 || interval > maxInterval {
+		interval = maxInterval
 	}
+	if interval < minInterval {
+		interval = minInterval
+	}
+	return interval
+}
 
-	return c
+// intervalClasses groups instances by metrics update interval.
+func intervalClasses(instances []sessions.Instance) map[time.Duration][]sessions.Instance {
+	res := make(map[time.Duration][]sessions.Instance)
+	for _, instance := range instances {
+		interval := scrapeInterval(instance)
+		res[interval] = append(res[interval], instance)
+	}
+	return res
 }
 
 func getEnabledInstances(instances []sessions.Instance) []sessions.Instance {
@@ -80,14 +120,52 @@ func getEnabledInstances(instances []sessions.Instance) []sessions.Instance {
 }
 
 // setMetrics saves latest scraped metrics.
-func (c *Collector) setMetrics(m map[string][]prometheus.Metric) {
+func (c *Collector) setMetrics(res *scrapeResult) {
+	now := time.Now()
 	c.rw.Lock()
-	for id, metrics := range m {
+	for id, metrics := range res.metrics {
 		c.metrics[id] = metrics
+		c.times[id] = res.times[id]
+		c.labels[id] = res.labels[id]
+	}
+	if c.config.Buffer > 0 {
+		for id, events := range res.events {
+			c.events[id] = addEvents(c.events[id], events, c.config.Buffer, now)
+		}
 	}
+	c.addWindowEvents(res, now)
+	c.removeExpired(now)
 	c.rw.Unlock()
 }
 
+// removeExpired removes metrics, buffered events, and window statistics of expired instances,
+// and then all their data after removeAfter TTLs. It should be called with write lock held.
+func (c *Collector) removeExpired(now time.Time) {
+	if c.config.TTL == 0 {
+		return
+	}
+
+	for id, t := range c.times {
+		age := now.Sub(t)
+		if !c.expired(age) {
+			continue
+		}
+
+		delete(c.metrics, id)
+		delete(c.events, id)
+		delete(c.windows, id)
+		if age > removeAfter*c.config.TTL {
+			delete(c.times, id)
+			delete(c.labels, id)
+		}
+	}
+}
+
+// expired returns true if instance's metrics should not be exposed anymore.
+func (c *Collector) expired(age time.Duration) bool {
+	return c.config.TTL > 0 && age > c.config.TTL
+}
+
 // Describe implements prometheus.Collector.
 func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 	// unchecked collector
@@ -95,11 +173,34 @@ func (c *Collector) Describe(ch chan<- *prometheus.Desc) {
 
 // Collect implements prometheus.Collector.
 func (c *Collector) Collect(ch chan<- prometheus.Metric) {
+	c.status.Collect(ch)
+
+	c.updateWindowStats()
+
 	c.rw.RLock()
 	defer c.rw.RUnlock()
 
-	for _, metrics := range c.metrics {
-		for _, m := range metrics {
+	now := time.Now()
+	for id, t := range c.times {
+		// age is exposed even for expired instances, so their absence can be alerted on
+		age := now.Sub(t)
+		desc := prometheus.NewDesc("rdsosmetrics_last_event_age_seconds", "Time since the latest enhanced monitoring event.", nil, c.labels[id])
+		ch <- prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, age.Seconds())
+
+		if c.expired(age) {
+			continue
+		}
+		if w := c.windows[id]; w != nil {
+			for _, m := range w.stats {
+				ch <- m
+			}
+		}
+
+		// in buffered mode, metrics are added by Gatherer
+		if c.config.Buffer > 0 {
+			continue
+		}
+		for _, m := range c.metrics[id] {
 			ch <- m
 		}
 	}
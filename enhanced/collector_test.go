@@ -0,0 +1,82 @@
+package enhanced
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/percona/exporter_shared/helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/sessions"
+	"github.com/percona/rds_exporter/status"
+)
+
+func TestCollectorTTL(t *testing.T) {
+	metric := func(instance string) prometheus.Metric {
+		desc := prometheus.NewDesc("rdsosmetrics_General_numVCPUs", "The number of virtual CPUs for the DB instance.", nil, prometheus.Labels{"instance": instance})
+		return prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, 2)
+	}
+
+	now := time.Now()
+	c := &Collector{
+		config:  config.Enhanced{TTL: 5 * time.Minute},
+		status:  status.New("enhanced"),
+		metrics: make(map[string][]prometheus.Metric),
+		times:   make(map[string]time.Time),
+		labels:  make(map[string]prometheus.Labels),
+	}
+	c.setMetrics(&scrapeResult{
+		metrics: map[string][]prometheus.Metric{"db-fresh": {metric("fresh")}, "db-stale": {metric("stale")}, "db-gone": {metric("gone")}},
+		times:   map[string]time.Time{"db-fresh": now.Add(-time.Minute), "db-stale": now.Add(-30 * time.Minute), "db-gone": now.Add(-2 * time.Hour)},
+		labels:  map[string]prometheus.Labels{"db-fresh": {"instance": "fresh"}, "db-stale": {"instance": "stale"}, "db-gone": {"instance": "gone"}},
+	})
+
+	ages := make(map[string]float64)
+	var numVCPUs []string
+	for _, m := range helpers.ReadMetrics(helpers.CollectMetrics(c)) {
+		switch {
+		case m.Name == "rdsosmetrics_last_event_age_seconds":
+			ages[m.Labels["instance"]] = m.Value
+		case strings.HasPrefix(m.Name, "rdsosmetrics_"):
+			numVCPUs = append(numVCPUs, m.Labels["instance"])
+		}
+	}
+
+	assert.Equal(t, []string{"fresh"}, numVCPUs, "stale instance's metrics should be dropped")
+	assert.Len(t, ages, 2, "instance should be removed after several TTLs")
+	assert.InDelta(t, 60, ages["fresh"], 10)
+	assert.InDelta(t, 1800, ages["stale"], 10)
+
+	assert.Len(t, c.metrics, 1, "stale instance's metrics should be removed")
+	assert.Contains(t, c.metrics, "db-fresh")
+	assert.Len(t, c.times, 2)
+	assert.NotContains(t, c.times, "db-gone")
+	assert.Len(t, c.labels, 2)
+	assert.NotContains(t, c.labels, "db-gone")
+}
+
+func TestIntervalClasses(t *testing.T) {
+	instances := []sessions.Instance{
+		{Instance: "one-second", EnhancedMonitoringInterval: time.Second},
+		{Instance: "five-seconds", EnhancedMonitoringInterval: 5 * time.Second},
+		{Instance: "one-minute", EnhancedMonitoringInterval: time.Minute},
+		{Instance: "unknown"},
+		{Instance: "another-five-seconds", EnhancedMonitoringInterval: 5 * time.Second},
+	}
+
+	classes := intervalClasses(instances)
+	names := make(map[time.Duration][]string)
+	for interval, instances := range classes {
+		for _, instance := range instances {
+			names[interval] = append(names[interval], instance.Instance)
+		}
+	}
+	assert.Equal(t, map[time.Duration][]string{
+		minInterval:     {"one-second"},
+		5 * time.Second: {"five-seconds", "another-five-seconds"},
+		maxInterval:     {"one-minute", "unknown"},
+	}, names)
+}
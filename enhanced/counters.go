@@ -0,0 +1,160 @@
+package enhanced
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// maxCounterGap is the maximal interval between events that is integrated into synthesized counters.
+// Longer intervals (for example, while instance or exporter was restarting, or enhanced monitoring was disabled)
+// are skipped: counters do not increase for them.
+const maxCounterGap = 5 * time.Minute
+
+// counterHelps contains help texts for synthesized node_exporter-like counters.
+//
+//nolint:lll
+var counterHelps = map[string]string{
+	"node_cpu_seconds_total":            "Seconds the CPUs spent in each mode, synthesized from enhanced monitoring percentages.",
+	"node_network_receive_bytes_total":  "Network device statistic receive_bytes, synthesized from enhanced monitoring rates.",
+	"node_network_transmit_bytes_total": "Network device statistic transmit_bytes, synthesized from enhanced monitoring rates.",
+	"node_disk_reads_completed_total":   "The total number of reads completed successfully, synthesized from enhanced monitoring rates.",
+	"node_disk_writes_completed_total":  "The total number of writes completed successfully, synthesized from enhanced monitoring rates.",
+	"node_disk_read_bytes_total":        "The total number of bytes read successfully, synthesized from enhanced monitoring rates.",
+	"node_disk_written_bytes_total":     "The total number of bytes written successfully, synthesized from enhanced monitoring rates.",
+}
+
+// cpuModes maps cpuUtilization fields to node_exporter's node_cpu_seconds_total modes.
+// Total is a sum of other modes, and guest time is already included in user time, so both are skipped.
+var cpuModes = map[string]func(*cpuUtilization) float64{
+	"idle":    func(c *cpuUtilization) float64 { return c.Idle },
+	"iowait":  func(c *cpuUtilization) float64 { return c.Wait },
+	"nice":    func(c *cpuUtilization) float64 { return c.Nice },
+	"softirq": func(c *cpuUtilization) float64 { return c.Irq }, // irq field is documented as software interrupts
+	"steal":   func(c *cpuUtilization) float64 { return c.Steal },
+	"system":  func(c *cpuUtilization) float64 { return c.System },
+	"user":    func(c *cpuUtilization) float64 { return c.User },
+}
+
+// counterKey identifies a single synthesized counter of a single instance.
+type counterKey struct {
+	name   string
+	device string // for disk and network counters
+	cpu    int    // for CPU counters
+	mode   string // for CPU counters
+}
+
+// counters integrates rates from successive enhanced monitoring events of a single instance
+// into monotonic node_exporter-like counters.
+type counters struct {
+	last   time.Time // timestamp of the last integrated event
+	values map[counterKey]float64
+}
+
+func newCounters() *counters {
+	return &counters{
+		values: make(map[counterKey]float64),
+	}
+}
+
+// add integrates rates from given event over the interval since the previous event.
+// Events should be added in timestamp order; already integrated events are ignored.
+func (c *counters) add(m *osMetrics, timestamp time.Time) {
+	if !timestamp.After(c.last) {
+		return
+	}
+
+	dt := timestamp.Sub(c.last)
+	first := c.last.IsZero()
+	c.last = timestamp
+	if first || dt > maxCounterGap {
+		// just remember the timestamp; make counters exist with zero values for the first event
+		dt = 0
+	}
+	seconds := dt.Seconds()
+
+	inc := func(name, device string, rate float64) {
+		c.values[counterKey{name: name, device: device}] += rate * seconds
+	}
+
+	// enhanced monitoring reports CPU utilization of all vCPUs together, like node_cpu_average{cpu="All"};
+	// it is spread evenly between vCPUs, so queries like avg by(instance)(rate(node_cpu_seconds_total[5m])) work
+	vCPUs := m.NumVCPUs
+	if vCPUs < 1 {
+		vCPUs = 1
+	}
+	for k := range c.values {
+		if k.mode != "" && k.cpu >= vCPUs {
+			delete(c.values, k) // instance class was changed
+		}
+	}
+	for cpu := 0; cpu < vCPUs; cpu++ {
+		for mode, f := range cpuModes {
+			c.values[counterKey{name: "node_cpu_seconds_total", cpu: cpu, mode: mode}] += f(&m.CPUUtilization) / 100 * seconds
+		}
+	}
+
+	for _, n := range m.Network {
+		inc("node_network_receive_bytes_total", n.Interface, n.Rx)
+		inc("node_network_transmit_bytes_total", n.Interface, n.Tx)
+	}
+
+	devices := make(map[string]struct{}, len(m.DiskIO))
+	for _, d := range m.DiskIO {
+		devices[d.Device] = struct{}{}
+		c.addDisk(&d, inc)
+	}
+	for _, d := range m.PhysicalDeviceIO {
+		// skip devices already reported in diskIO to avoid duplicates, like makePrometheusMetrics does
+		if _, ok := devices[d.Device]; !ok {
+			c.addDisk(&d, inc)
+		}
+	}
+}
+
+// addDisk integrates rates of a single disk device.
+func (c *counters) addDisk(d *diskIO, inc func(name, device string, rate float64)) {
+	inc("node_disk_reads_completed_total", d.Device, d.ReadIOsPS)
+	inc("node_disk_writes_completed_total", d.Device, d.WriteIOsPS)
+
+	// non-Aurora devices have node_disk_{read,written}_bytes_total from readKb and writeKb totals
+	if d.ReadKb == nil && d.ReadThroughput != nil {
+		inc("node_disk_read_bytes_total", d.Device, *d.ReadThroughput)
+	}
+	if d.WriteKb == nil && d.WriteThroughput != nil {
+		inc("node_disk_written_bytes_total", d.Device, *d.WriteThroughput)
+	}
+}
+
+// metrics returns Prometheus metrics for all counters.
+func (c *counters) metrics(constLabels prometheus.Labels) []prometheus.Metric {
+	keys := make([]counterKey, 0, len(c.values))
+	for k := range c.values {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].name != keys[j].name {
+			return keys[i].name < keys[j].name
+		}
+		if keys[i].device != keys[j].device {
+			return keys[i].device < keys[j].device
+		}
+		if keys[i].cpu != keys[j].cpu {
+			return keys[i].cpu < keys[j].cpu
+		}
+		return keys[i].mode < keys[j].mode
+	})
+
+	res := make([]prometheus.Metric, 0, len(keys))
+	for _, k := range keys {
+		labelNames, labelValues := []string{"device"}, []string{k.device}
+		if k.mode != "" {
+			labelNames, labelValues = []string{"cpu", "mode"}, []string{strconv.Itoa(k.cpu), k.mode}
+		}
+		desc := prometheus.NewDesc(k.name, counterHelps[k.name], labelNames, constLabels)
+		res = append(res, prometheus.MustNewConstMetric(desc, prometheus.CounterValue, c.values[k], labelValues...))
+	}
+	return res
+}
@@ -0,0 +1,125 @@
+package enhanced
+
+import (
+	"testing"
+	"time"
+
+	"github.com/percona/exporter_shared/helpers"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCounters(t *testing.T) {
+	parse := func(s string) *osMetrics {
+		m, err := parseOSMetrics([]byte(s), true)
+		require.NoError(t, err)
+		return m
+	}
+	values := func(c *counters) map[string]float64 {
+		res := make(map[string]float64)
+		for _, m := range helpers.ReadMetrics(c.metrics(nil)) {
+			if m.Name == "node_cpu_seconds_total" {
+				continue
+			}
+			res[m.Name+"/"+m.Labels["device"]] = m.Value
+		}
+		return res
+	}
+
+	aurora := parse(`{
+		"diskIO": [{"device": "rdsdev", "readIOsPS": 2, "writeIOsPS": 4, "readThroughput": 100, "writeThroughput": 200}],
+		"network": [{"interface": "eth0", "rx": 1000, "tx": 500}]
+	}`)
+	mysql := parse(`{
+		"diskIO": [{"device": "rdsdev", "readIOsPS": 1, "writeIOsPS": 1, "readKb": 10, "writeKb": 20}],
+		"physicalDeviceIO": [{"device": "nvme1n1", "readIOsPS": 3, "writeIOsPS": 5}]
+	}`)
+
+	t0 := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Aurora", func(t *testing.T) {
+		c := newCounters()
+		c.add(aurora, t0)
+		assert.Equal(t, map[string]float64{
+			"node_disk_read_bytes_total/rdsdev":       0,
+			"node_disk_reads_completed_total/rdsdev":  0,
+			"node_disk_writes_completed_total/rdsdev": 0,
+			"node_disk_written_bytes_total/rdsdev":    0,
+			"node_network_receive_bytes_total/eth0":   0,
+			"node_network_transmit_bytes_total/eth0":  0,
+		}, values(c), "first event only sets a starting point")
+
+		c.add(aurora, t0.Add(10*time.Second))
+		c.add(aurora, t0.Add(10*time.Second)) // the same event may be returned again
+		c.add(aurora, t0.Add(5*time.Second))  // older events are ignored
+		c.add(aurora, t0.Add(20*time.Second))
+		assert.Equal(t, map[string]float64{
+			"node_disk_read_bytes_total/rdsdev":       2000,
+			"node_disk_reads_completed_total/rdsdev":  40,
+			"node_disk_writes_completed_total/rdsdev": 80,
+			"node_disk_written_bytes_total/rdsdev":    4000,
+			"node_network_receive_bytes_total/eth0":   20000,
+			"node_network_transmit_bytes_total/eth0":  10000,
+		}, values(c))
+
+		c.add(aurora, t0.Add(20*time.Second+maxCounterGap+time.Second)) // gap is not integrated
+		c.add(aurora, t0.Add(20*time.Second+maxCounterGap+2*time.Second))
+		assert.Equal(t, 21000.0, values(c)["node_network_receive_bytes_total/eth0"])
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		c := newCounters()
+		c.add(mysql, t0)
+		c.add(mysql, t0.Add(time.Minute))
+		assert.Equal(t, map[string]float64{
+			"node_disk_reads_completed_total/nvme1n1":  180,
+			"node_disk_reads_completed_total/rdsdev":   60,
+			"node_disk_writes_completed_total/nvme1n1": 300,
+			"node_disk_writes_completed_total/rdsdev":  60,
+		}, values(c), "bytes counters are reported from readKb and writeKb")
+	})
+	t.Run("CPU", func(t *testing.T) {
+		m := parse(`{
+			"numVCPUs": 4,
+			"cpuUtilization": {"guest": 1, "idle": 50, "irq": 2, "nice": 3, "steal": 4, "system": 10, "total": 50, "user": 25, "wait": 5}
+		}`)
+
+		c := newCounters()
+		c.add(m, t0)
+		c.add(m, t0.Add(10*time.Second))
+
+		actual := make(map[string]float64)
+		for _, m := range helpers.ReadMetrics(c.metrics(nil)) {
+			if m.Name == "node_cpu_seconds_total" {
+				actual[m.Labels["cpu"]+"/"+m.Labels["mode"]] = m.Value
+			}
+		}
+		expected := make(map[string]float64)
+		for _, cpu := range []string{"0", "1", "2", "3"} {
+			for mode, v := range map[string]float64{
+				"idle":    5,
+				"iowait":  0.5,
+				"nice":    0.3,
+				"softirq": 0.2,
+				"steal":   0.4,
+				"system":  1,
+				"user":    2.5,
+			} {
+				expected[cpu+"/"+mode] = v
+			}
+		}
+		assert.InDeltaMapValues(t, expected, actual, 1e-9, "each vCPU should have a share of utilization")
+
+		// series of removed vCPUs are dropped when instance class changes
+		m.NumVCPUs = 2
+		c.add(m, t0.Add(20*time.Second))
+		var cpus []string
+		for _, m := range helpers.ReadMetrics(c.metrics(nil)) {
+			if m.Name == "node_cpu_seconds_total" && m.Labels["mode"] == "idle" {
+				cpus = append(cpus, m.Labels["cpu"])
+				assert.InDelta(t, 10, m.Value, 1e-9)
+			}
+		}
+		assert.Equal(t, []string{"0", "1"}, cpus)
+	})
+}
@@ -5,10 +5,16 @@ import (
 	"encoding/json"
 	"flag"
 	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
 	"path/filepath"
 	"strings"
 	"testing"
 
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/credentials"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -52,3 +58,32 @@ func writeTestDataMetrics(t *testing.T, instance string, metrics []string) {
 	err := ioutil.WriteFile(filepath.Join("testdata", instance+".txt"), b, 0666)
 	require.NoError(t, err)
 }
+
+// fakeLogEvent is a single enhanced monitoring event returned by fake CloudWatch Logs.
+type fakeLogEvent struct {
+	EventID       string `json:"eventId"`
+	LogStreamName string `json:"logStreamName"`
+	Timestamp     int64  `json:"timestamp"`
+	IngestionTime int64  `json:"ingestionTime"`
+	Message       string `json:"message"`
+}
+
+// newFakeLogs starts a fake CloudWatch Logs FilterLogEvents API server that returns given events;
+// it is stopped when the test ends. It returns AWS session for that server.
+func newFakeLogs(t *testing.T, events []fakeLogEvent) *session.Session {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		assert.Equal(t, "Logs_20140328.FilterLogEvents", req.Header.Get("X-Amz-Target"))
+		rw.Header().Set("Content-Type", "application/x-amz-json-1.1")
+		assert.NoError(t, json.NewEncoder(rw).Encode(map[string]interface{}{"events": events}))
+	}))
+	t.Cleanup(srv.Close)
+
+	return session.Must(session.NewSession(&aws.Config{
+		Region:      aws.String("us-west-2"),
+		Endpoint:    aws.String(srv.URL),
+		Credentials: credentials.NewStaticCredentials("fake", "fake", ""),
+		MaxRetries:  aws.Int(0),
+	}))
+}
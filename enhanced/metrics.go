@@ -4,11 +4,17 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"math"
 	"reflect"
+	"regexp"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/percona/rds_exporter/config"
 )
 
 // osMetrics represents available Enhanced Monitoring OS metrics from CloudWatch Logs.
@@ -38,7 +44,7 @@ type osMetrics struct {
 	Swap              swap              `json:"swap"`
 	Tasks             tasks             `json:"tasks"`
 
-	// TODO Handle this: https://jira.percona.com/browse/PMM-3835
+	// physical devices beneath the storage layer
 	PhysicalDeviceIO []diskIO `json:"physicalDeviceIO"`
 }
 
@@ -128,17 +134,41 @@ type network struct {
 
 //nolint:lll
 type processList struct {
-	CPUUsedPC    float64 `json:"cpuUsedPc"    help:"The percentage of CPU used by the process."`
-	ID           int     `json:"id"           help:"The identifier of the process."`
-	MemoryUsedPC float64 `json:"memoryUsedPc" help:"The amount of memory used by the process, in kilobytes."`
-	Name         string  `json:"name"         help:"The name of the process."`
-	ParentID     int     `json:"parentID"     help:"The process identifier for the parent process of the process."`
-	RSS          int     `json:"rss"          help:"The amount of RAM allocated to the process, in kilobytes."`
-	TGID         int     `json:"tgid"         help:"The thread group identifier, which is a number representing the process ID to which a thread belongs. This identifier is used to group threads from the same process."`
-	VSS          int     `json:"vss"          help:"The amount of virtual memory allocated to the process, in kilobytes."`
+	CPUUsedPC    float64  `json:"cpuUsedPc"    help:"The percentage of CPU used by the process."`
+	ID           int      `json:"id"           help:"The identifier of the process."`
+	MemoryUsedPC float64  `json:"memoryUsedPc" help:"The amount of memory used by the process, in kilobytes."`
+	Name         string   `json:"name"         help:"The name of the process."`
+	ParentID     int      `json:"parentID"     help:"The process identifier for the parent process of the process."`
+	RSS          int      `json:"rss"          help:"The amount of RAM allocated to the process, in kilobytes."`
+	TGID         int      `json:"tgid"         help:"The thread group identifier, which is a number representing the process ID to which a thread belongs. This identifier is used to group threads from the same process."`
+	VSS          int      `json:"vss"          help:"The amount of virtual memory allocated to the process, in kilobytes."`
+	VMLimit      *vmLimit `json:"vmlimit"      help:"The virtual memory limit of the process, in kilobytes."`
+}
+
+// vmLimit represents processList vmlimit value: a number or "unlimited" string.
+type vmLimit struct {
+	value     float64
+	unlimited bool
+}
 
-	// TODO Handle this: https://jira.percona.com/browse/PMM-5150
-	VMLimit interface{} `json:"vmlimit" help:"-"`
+// UnmarshalJSON implements json.Unmarshaler.
+func (v *vmLimit) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		// not a string
+		return json.Unmarshal(b, &v.value)
+	}
+
+	if s == "unlimited" {
+		v.unlimited = true
+		return nil
+	}
+	f, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return fmt.Errorf("failed to parse vmlimit %q", s)
+	}
+	v.value = f
+	return nil
 }
 
 //nolint:lll
@@ -175,6 +205,38 @@ func parseOSMetrics(b []byte, disallowUnknownFields bool) (*osMetrics, error) {
 	return &m, nil
 }
 
+// uptimeRE matches uptime strings like "01:45:58", "1 day, 07:11:58", or "332 days, 1:07:34.25".
+var uptimeRE = regexp.MustCompile(`^(?:(\d+) days?,?\s*)?(?:(\d+):(\d{1,2}):(\d{1,2}(?:\.\d+)?))?$`)
+
+// parseUptime parses uptime string.
+func parseUptime(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	m := uptimeRE.FindStringSubmatch(s)
+	if s == "" || m == nil {
+		return 0, fmt.Errorf("failed to parse uptime %q", s)
+	}
+
+	var res time.Duration
+	for i, unit := range []time.Duration{24 * time.Hour, time.Hour, time.Minute} {
+		if m[i+1] == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(m[i+1], 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse uptime %q: %s", s, err)
+		}
+		res += time.Duration(n) * unit
+	}
+	if m[4] != "" {
+		f, err := strconv.ParseFloat(m[4], 64)
+		if err != nil {
+			return 0, fmt.Errorf("failed to parse uptime %q: %s", s, err)
+		}
+		res += time.Duration(f * float64(time.Second))
+	}
+	return res, nil
+}
+
 // makeGauge returns Prometheus gauge for given reflect.Value.
 func makeGauge(desc *prometheus.Desc, labelValues []string, value reflect.Value) prometheus.Metric {
 	// skip nil fields
@@ -239,8 +301,8 @@ func makeNodeCPUMetrics(s *cpuUtilization, constLabels prometheus.Labels) []prom
 	return res
 }
 
-// makeRDSDiskIOMetrics returns rdsosmetrics_diskIO_ metrics.
-func makeRDSDiskIOMetrics(s *diskIO, constLabels prometheus.Labels) []prometheus.Metric {
+// makeRDSDiskIOMetrics returns rdsosmetrics_diskIO_ or rdsosmetrics_physicalDeviceIO_ metrics (depending on namePrefix).
+func makeRDSDiskIOMetrics(s *diskIO, namePrefix string, constLabels prometheus.Labels) []prometheus.Metric {
 	// move device name to label
 	labelKeys := []string{"device"}
 	labelValues := []string{s.Device}
@@ -254,7 +316,7 @@ func makeRDSDiskIOMetrics(s *diskIO, constLabels prometheus.Labels) []prometheus
 		if name == "device" {
 			continue
 		}
-		desc := prometheus.NewDesc("rdsosmetrics_diskIO_"+name, help, labelKeys, constLabels)
+		desc := prometheus.NewDesc(namePrefix+name, help, labelKeys, constLabels)
 		m := makeGauge(desc, labelValues, v.Field(i))
 		if m != nil {
 			res = append(res, m)
@@ -340,11 +402,9 @@ func makeNodeLoadMetrics(s *loadAverageMinute, constLabels prometheus.Labels) []
 	m5 := prometheus.MustNewConstMetric(desc5, prometheus.GaugeValue, s.Five)
 	desc15 := prometheus.NewDesc("node_load15", "The number of processes requesting CPU time over the 15 minute.", nil, constLabels)
 	m15 := prometheus.MustNewConstMetric(desc15, prometheus.GaugeValue, s.Fifteen)
-	return []prometheus.Metric{m,m5,m15}
+	return []prometheus.Metric{m, m5, m15}
 }
 
-
-
 // makeNodeMemoryMetrics returns node_exporter-like node_memory_ metrics.
 func makeNodeMemoryMetrics(s *memory, constLabels prometheus.Labels) []prometheus.Metric {
 	t := reflect.TypeOf(*s)
@@ -391,7 +451,7 @@ func makeRDSNetworkMetrics(s *network, constLabels prometheus.Labels) []promethe
 }
 
 // makeRDSProcessListMetrics returns rdsosmetrics_processList_ metrics.
-func makeRDSProcessListMetrics(s *processList, constLabels prometheus.Labels) []prometheus.Metric {
+func makeRDSProcessListMetrics(s *processList, constLabels prometheus.Labels, cfg config.Enhanced) []prometheus.Metric {
 	// move process name, ID, parent ID, thread ID to labels
 	labelKeys := []string{"name", "id", "parentID", "tgid"}
 	labelValues := []string{s.Name, strconv.Itoa(s.ID), strconv.Itoa(s.ParentID), strconv.Itoa(s.TGID)}
@@ -402,15 +462,24 @@ func makeRDSProcessListMetrics(s *processList, constLabels prometheus.Labels) []
 	for i := 0; i < t.NumField(); i++ {
 		tags := t.Field(i).Tag
 		name, help := tags.Get("json"), tags.Get("help")
-		if help == "-" {
-			continue
-		}
-
+		desc := prometheus.NewDesc("rdsosmetrics_processList_"+name, help, labelKeys, constLabels)
 		switch name {
 		case "name", "id", "parentID", "tgid":
 			continue
+		case "vmlimit":
+			if s.VMLimit == nil {
+				continue
+			}
+			value := s.VMLimit.value
+			if s.VMLimit.unlimited {
+				if cfg.UnlimitedVMLimit != "inf" {
+					continue
+				}
+				value = math.Inf(1)
+			}
+			res = append(res, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value, labelValues...))
+			continue
 		}
-		desc := prometheus.NewDesc("rdsosmetrics_processList_"+name, help, labelKeys, constLabels)
 		m := makeGauge(desc, labelValues, v.Field(i))
 		if m != nil {
 			res = append(res, m)
@@ -419,6 +488,95 @@ func makeRDSProcessListMetrics(s *processList, constLabels prometheus.Labels) []
 	return res
 }
 
+// processGroup returns a name of the group for given process name:
+// a part before colon ("postgres" for "postgres: checkpointer"), or the whole name ("OS processes").
+func processGroup(name string) string {
+	if i := strings.Index(name, ":"); i >= 0 {
+		name = name[:i]
+	}
+	if name = strings.TrimSpace(name); name == "" {
+		return "unknown"
+	}
+	return name
+}
+
+// makeRDSProcessGroupMetrics returns rdsosmetrics_processGroup_ metrics with processList metrics summed by process group.
+func makeRDSProcessGroupMetrics(processes []processList, constLabels prometheus.Labels) []prometheus.Metric {
+	type group struct {
+		cpu, memory float64
+		rss, vss    int
+		count       int
+	}
+	var names []string
+	groups := make(map[string]*group)
+	for _, p := range processes {
+		name := processGroup(p.Name)
+		g := groups[name]
+		if g == nil {
+			g = new(group)
+			groups[name] = g
+			names = append(names, name)
+		}
+		g.cpu += p.CPUUsedPC
+		g.memory += p.MemoryUsedPC
+		g.rss += p.RSS
+		g.vss += p.VSS
+		g.count++
+	}
+
+	labelKeys := []string{"group"}
+	cpuDesc := prometheus.NewDesc("rdsosmetrics_processGroup_cpuUsedPc", "The percentage of CPU used by the processes of the group.", labelKeys, constLabels)
+	memoryDesc := prometheus.NewDesc("rdsosmetrics_processGroup_memoryUsedPc", "The percentage of memory used by the processes of the group.", labelKeys, constLabels)
+	rssDesc := prometheus.NewDesc("rdsosmetrics_processGroup_rss", "The amount of RAM allocated to the processes of the group, in kilobytes.", labelKeys, constLabels)
+	vssDesc := prometheus.NewDesc("rdsosmetrics_processGroup_vss", "The amount of virtual memory allocated to the processes of the group, in kilobytes.", labelKeys, constLabels)
+	countDesc := prometheus.NewDesc("rdsosmetrics_processGroup_count", "The number of processes in the group.", labelKeys, constLabels)
+	res := make([]prometheus.Metric, 0, len(names)*5)
+	for _, name := range names {
+		g := groups[name]
+		res = append(res,
+			prometheus.MustNewConstMetric(cpuDesc, prometheus.GaugeValue, g.cpu, name),
+			prometheus.MustNewConstMetric(memoryDesc, prometheus.GaugeValue, g.memory, name),
+			prometheus.MustNewConstMetric(rssDesc, prometheus.GaugeValue, float64(g.rss), name),
+			prometheus.MustNewConstMetric(vssDesc, prometheus.GaugeValue, float64(g.vss), name),
+			prometheus.MustNewConstMetric(countDesc, prometheus.GaugeValue, float64(g.count), name),
+		)
+	}
+	return res
+}
+
+// makeRDSProcessTopMetrics returns rdsosmetrics_processTop_ metrics for at most n processes using the most CPU.
+// Processes are identified by rank and process group labels to keep cardinality bounded.
+func makeRDSProcessTopMetrics(processes []processList, n int, constLabels prometheus.Labels) []prometheus.Metric {
+	top := make([]processList, len(processes))
+	copy(top, processes)
+	sort.SliceStable(top, func(i, j int) bool {
+		if top[i].CPUUsedPC != top[j].CPUUsedPC {
+			return top[i].CPUUsedPC > top[j].CPUUsedPC
+		}
+		return top[i].RSS > top[j].RSS
+	})
+	if len(top) > n {
+		top = top[:n]
+	}
+
+	labelKeys := []string{"rank", "group"}
+	cpuDesc := prometheus.NewDesc("rdsosmetrics_processTop_cpuUsedPc", "The percentage of CPU used by the process.", labelKeys, constLabels)
+	memoryDesc := prometheus.NewDesc("rdsosmetrics_processTop_memoryUsedPc", "The percentage of memory used by the process.", labelKeys, constLabels)
+	rssDesc := prometheus.NewDesc("rdsosmetrics_processTop_rss", "The amount of RAM allocated to the process, in kilobytes.", labelKeys, constLabels)
+	vssDesc := prometheus.NewDesc("rdsosmetrics_processTop_vss", "The amount of virtual memory allocated to the process, in kilobytes.", labelKeys, constLabels)
+	res := make([]prometheus.Metric, 0, len(top)*4)
+	for i, p := range top {
+		labelValues := []string{strconv.Itoa(i + 1), processGroup(p.Name)}
+		res = append(res,
+			prometheus.MustNewConstMetric(cpuDesc, prometheus.GaugeValue, p.CPUUsedPC, labelValues...),
+			prometheus.MustNewConstMetric(memoryDesc, prometheus.GaugeValue, p.MemoryUsedPC, labelValues...),
+			prometheus.MustNewConstMetric(rssDesc, prometheus.GaugeValue, float64(p.RSS), labelValues...),
+			prometheus.MustNewConstMetric(vssDesc, prometheus.GaugeValue, float64(p.VSS), labelValues...),
+		)
+	}
+	return res
+}
+
 // makeNodeMemorySwapMetrics returns node_exporter-like node_memory_ metrics for swap.
 func makeNodeMemorySwapMetrics(s *swap, constLabels prometheus.Labels) []prometheus.Metric {
 	t := reflect.TypeOf(*s)
@@ -450,10 +608,8 @@ func makeNodeProcsMetrics(s *tasks, constLabels prometheus.Labels) []prometheus.
 	return res
 }
 
-// makePrometheusMetrics returns all Prometheus metrics for given osMetrics.
-func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]string) []prometheus.Metric {
-	res := make([]prometheus.Metric, 0, 100)
-
+// makeConstLabels returns constant labels for all instance's metrics.
+func (m *osMetrics) makeConstLabels(region string, labels map[string]string) prometheus.Labels {
 	constLabels := prometheus.Labels{
 		"region":   region,
 		"instance": m.InstanceID,
@@ -465,6 +621,14 @@ func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]strin
 			constLabels[n] = v
 		}
 	}
+	return constLabels
+}
+
+// makePrometheusMetrics returns all Prometheus metrics for given osMetrics.
+func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]string, cfg config.Enhanced) []prometheus.Metric {
+	res := make([]prometheus.Metric, 0, 100)
+
+	constLabels := m.makeConstLabels(region, labels)
 
 	res = append(res, prometheus.MustNewConstMetric(
 		prometheus.NewDesc("rdsosmetrics_timestamp", "Metrics timestamp (UNIX seconds).", nil, constLabels),
@@ -472,7 +636,18 @@ func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]strin
 		float64(m.Timestamp.Unix()),
 	))
 
-	// TODO Parse uptime: https://jira.percona.com/browse/PMM-2131
+	if uptime, err := parseUptime(m.Uptime); err == nil {
+		res = append(res, prometheus.MustNewConstMetric(
+			prometheus.NewDesc("rdsosmetrics_uptime_seconds", "The amount of time that the DB instance has been active, in seconds.", nil, constLabels),
+			prometheus.GaugeValue,
+			uptime.Seconds(),
+		))
+		res = append(res, prometheus.MustNewConstMetric(
+			prometheus.NewDesc("node_boot_time_seconds", "Node boot time, in unixtime.", nil, constLabels),
+			prometheus.GaugeValue,
+			float64(m.Timestamp.Add(-uptime).Unix()),
+		))
+	}
 
 	res = append(res, prometheus.MustNewConstMetric(
 		prometheus.NewDesc("rdsosmetrics_General_numVCPUs", "The number of virtual CPUs for the DB instance.", nil, constLabels),
@@ -487,13 +662,25 @@ func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]strin
 	metrics = makeNodeCPUMetrics(&m.CPUUtilization, constLabels)
 	res = append(res, metrics...)
 
+	devices := make(map[string]struct{}, len(m.DiskIO))
 	for _, disk := range m.DiskIO {
-		metrics = makeRDSDiskIOMetrics(&disk, constLabels)
+		devices[disk.Device] = struct{}{}
+		metrics = makeRDSDiskIOMetrics(&disk, "rdsosmetrics_diskIO_", constLabels)
 		res = append(res, metrics...)
 		metrics = makeNodeDiskMetrics(&disk, constLabels)
 		res = append(res, metrics...)
 	}
 
+	for _, disk := range m.PhysicalDeviceIO {
+		metrics = makeRDSDiskIOMetrics(&disk, "rdsosmetrics_physicalDeviceIO_", constLabels)
+		res = append(res, metrics...)
+		// skip node_exporter-like metrics for devices already reported in diskIO to avoid duplicates
+		if _, ok := devices[disk.Device]; !ok {
+			metrics = makeNodeDiskMetrics(&disk, constLabels)
+			res = append(res, metrics...)
+		}
+	}
+
 	for _, fs := range m.FileSys {
 		metrics = makeRDSFileSysMetrics(&fs, constLabels)
 		res = append(res, metrics...)
@@ -514,14 +701,23 @@ func (m *osMetrics) makePrometheusMetrics(region string, labels map[string]strin
 	for _, n := range m.Network {
 		metrics = makeRDSNetworkMetrics(&n, constLabels)
 		res = append(res, metrics...)
-		// we can't make node_exporter-like metrics: AWS gives us rates, node_exporter - total counters
+		// AWS gives us rates, node_exporter - total counters; they may be synthesized by the scraper
 	}
 
-	for _, p := range m.ProcessList {
-		metrics = makeRDSProcessListMetrics(&p, constLabels)
+	if cfg.ProcessList == "aggregate" {
+		metrics = makeRDSProcessGroupMetrics(m.ProcessList, constLabels)
+		res = append(res, metrics...)
+	} else {
+		for _, p := range m.ProcessList {
+			metrics = makeRDSProcessListMetrics(&p, constLabels, cfg)
+			res = append(res, metrics...)
+		}
+	}
+	if cfg.ProcessListTop > 0 {
+		metrics = makeRDSProcessTopMetrics(m.ProcessList, cfg.ProcessListTop, constLabels)
 		res = append(res, metrics...)
-		// no node_exporter-like metrics
 	}
+	// no node_exporter-like metrics for processes
 
 	metrics = makeGenericMetrics(m.Swap, "rdsosmetrics_swap_", constLabels)
 	res = append(res, metrics...)
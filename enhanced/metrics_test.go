@@ -1,12 +1,17 @@
 package enhanced
 
 import (
+	"fmt"
 	"sort"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/percona/exporter_shared/helpers"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"github.com/percona/rds_exporter/config"
 )
 
 func TestParse(t *testing.T) {
@@ -26,7 +31,7 @@ func TestParse(t *testing.T) {
 			m, err := parseOSMetrics(readTestDataJSON(t, data.instance), true)
 			require.NoError(t, err)
 
-			actualMetrics := helpers.ReadMetrics(m.makePrometheusMetrics(data.region, nil))
+			actualMetrics := helpers.ReadMetrics(m.makePrometheusMetrics(data.region, nil, config.Enhanced{}))
 			sort.Slice(actualMetrics, func(i, j int) bool { return actualMetrics[i].Less(actualMetrics[j]) })
 			actualLines := helpers.Format(helpers.WriteMetrics(actualMetrics))
 
@@ -45,9 +50,118 @@ func TestParse(t *testing.T) {
 	}
 }
 
+func TestPhysicalDeviceIOAurora(t *testing.T) {
+	for _, instance := range []string{"aurora-mysql-56", "aurora-psql-11"} {
+		instance := instance
+		t.Run(instance, func(t *testing.T) {
+			m, err := parseOSMetrics(readTestDataJSON(t, instance), true)
+			require.NoError(t, err)
+
+			// physicalDeviceIO is optional: captured Aurora instances report storage only in diskIO
+			assert.Empty(t, m.PhysicalDeviceIO)
+			for _, metric := range helpers.ReadMetrics(m.makePrometheusMetrics("us-east-1", nil, config.Enhanced{})) {
+				assert.False(t, strings.HasPrefix(metric.Name, "rdsosmetrics_physicalDeviceIO_"), metric.Name)
+			}
+		})
+	}
+}
+
+func TestVMLimit(t *testing.T) {
+	m, err := parseOSMetrics([]byte(`{"processList": [
+		{"name": "postgres", "id": 1, "vmlimit": "unlimited"},
+		{"name": "rdsadmin", "id": 2, "vmlimit": 1048576},
+		{"name": "aurora", "id": 3}
+	]}`), true)
+	require.NoError(t, err)
+	require.Len(t, m.ProcessList, 3)
+	assert.Equal(t, &vmLimit{unlimited: true}, m.ProcessList[0].VMLimit)
+	assert.Equal(t, &vmLimit{value: 1048576}, m.ProcessList[1].VMLimit)
+	assert.Nil(t, m.ProcessList[2].VMLimit)
+
+	_, err = parseOSMetrics([]byte(`{"processList": [{"vmlimit": "limited"}]}`), true)
+	assert.Error(t, err)
+
+	vmlimits := func(cfg config.Enhanced) []string {
+		var res []string
+		for _, metric := range helpers.ReadMetrics(m.makePrometheusMetrics("us-east-1", nil, cfg)) {
+			if metric.Name == "rdsosmetrics_processList_vmlimit" {
+				res = append(res, fmt.Sprintf("%s %v", metric.Labels["name"], metric.Value))
+			}
+		}
+		sort.Strings(res)
+		return res
+	}
+	assert.Equal(t, []string{"rdsadmin 1.048576e+06"}, vmlimits(config.Enhanced{}))
+	assert.Equal(t, []string{"postgres +Inf", "rdsadmin 1.048576e+06"}, vmlimits(config.Enhanced{UnlimitedVMLimit: "inf"}))
+}
+
+func TestProcessListAggregation(t *testing.T) {
+	m, err := parseOSMetrics([]byte(`{"processList": [
+		{"name": "postgres: checkpointer   ", "id": 10, "cpuUsedPc": 0.5, "memoryUsedPc": 1.5, "rss": 100, "vss": 1000},
+		{"name": "postgres: rdsadmin rdsadmin localhost(10830) idle", "id": 11, "cpuUsedPc": 2, "memoryUsedPc": 0.5, "rss": 50, "vss": 500},
+		{"name": "OS processes", "id": 0, "cpuUsedPc": 1, "memoryUsedPc": 3, "rss": 300, "vss": 3000},
+		{"name": "", "id": 12, "cpuUsedPc": 0.1}
+	]}`), true)
+	require.NoError(t, err)
+
+	values := func(cfg config.Enhanced) []string {
+		var res []string
+		for _, metric := range helpers.ReadMetrics(m.makePrometheusMetrics("us-east-1", nil, cfg)) {
+			if strings.HasPrefix(metric.Name, "rdsosmetrics_process") {
+				res = append(res, fmt.Sprintf("%s{group=%q,rank=%q,id=%q} %v",
+					metric.Name, metric.Labels["group"], metric.Labels["rank"], metric.Labels["id"], metric.Value))
+			}
+		}
+		sort.Strings(res)
+		return res
+	}
+
+	assert.Len(t, values(config.Enhanced{}), 4*4) // cpuUsedPc, memoryUsedPc, rss, vss for every process; vmlimit is absent
+
+	assert.Equal(t, []string{
+		`rdsosmetrics_processGroup_count{group="OS processes",rank="",id=""} 1`,
+		`rdsosmetrics_processGroup_count{group="postgres",rank="",id=""} 2`,
+		`rdsosmetrics_processGroup_count{group="unknown",rank="",id=""} 1`,
+		`rdsosmetrics_processGroup_cpuUsedPc{group="OS processes",rank="",id=""} 1`,
+		`rdsosmetrics_processGroup_cpuUsedPc{group="postgres",rank="",id=""} 2.5`,
+		`rdsosmetrics_processGroup_cpuUsedPc{group="unknown",rank="",id=""} 0.1`,
+		`rdsosmetrics_processGroup_memoryUsedPc{group="OS processes",rank="",id=""} 3`,
+		`rdsosmetrics_processGroup_memoryUsedPc{group="postgres",rank="",id=""} 2`,
+		`rdsosmetrics_processGroup_memoryUsedPc{group="unknown",rank="",id=""} 0`,
+		`rdsosmetrics_processGroup_rss{group="OS processes",rank="",id=""} 300`,
+		`rdsosmetrics_processGroup_rss{group="postgres",rank="",id=""} 150`,
+		`rdsosmetrics_processGroup_rss{group="unknown",rank="",id=""} 0`,
+		`rdsosmetrics_processGroup_vss{group="OS processes",rank="",id=""} 3000`,
+		`rdsosmetrics_processGroup_vss{group="postgres",rank="",id=""} 1500`,
+		`rdsosmetrics_processGroup_vss{group="unknown",rank="",id=""} 0`,
+		`rdsosmetrics_processTop_cpuUsedPc{group="OS processes",rank="2",id=""} 1`,
+		`rdsosmetrics_processTop_cpuUsedPc{group="postgres",rank="1",id=""} 2`,
+		`rdsosmetrics_processTop_memoryUsedPc{group="OS processes",rank="2",id=""} 3`,
+		`rdsosmetrics_processTop_memoryUsedPc{group="postgres",rank="1",id=""} 0.5`,
+		`rdsosmetrics_processTop_rss{group="OS processes",rank="2",id=""} 300`,
+		`rdsosmetrics_processTop_rss{group="postgres",rank="1",id=""} 50`,
+		`rdsosmetrics_processTop_vss{group="OS processes",rank="2",id=""} 3000`,
+		`rdsosmetrics_processTop_vss{group="postgres",rank="1",id=""} 500`,
+	}, values(config.Enhanced{ProcessList: "aggregate", ProcessListTop: 2}))
+}
+
 func TestParseUptime(t *testing.T) {
-	t.Skip("TODO Parse uptime https://jira.percona.com/browse/PMM-2131")
+	for s, expected := range map[string]time.Duration{
+		"01:45:58":              time.Hour + 45*time.Minute + 58*time.Second,
+		"1:45:58":               time.Hour + 45*time.Minute + 58*time.Second,
+		"1 day, 07:11:58":       31*time.Hour + 11*time.Minute + 58*time.Second,
+		"332 days, 01:07:34":    332*24*time.Hour + time.Hour + 7*time.Minute + 34*time.Second,
+		"2 days, 0:00:01.5":     48*time.Hour + 1500*time.Millisecond,
+		"5 days":                5 * 24 * time.Hour,
+		" 12 days, 23:59:59 \n": 12*24*time.Hour + 23*time.Hour + 59*time.Minute + 59*time.Second,
+	} {
+		actual, err := parseUptime(s)
+		assert.NoError(t, err, "%q", s)
+		assert.Equal(t, expected, actual, "%q", s)
+	}
 
-	_ = "01:45:58"
-	_ = "1 day, 07:11:58"
+	for _, s := range []string{"", "day, 01:02:03", "01:02", "1 week, 01:02:03"} {
+		_, err := parseUptime(s)
+		assert.Error(t, err, "%q", s)
+	}
 }
@@ -3,6 +3,7 @@ package enhanced
 import (
 	"context"
 	"fmt"
+	"sort"
 	"time"
 
 	"github.com/aws/aws-sdk-go/aws"
@@ -12,7 +13,9 @@ import (
 	"github.com/go-kit/log/level"
 	"github.com/prometheus/client_golang/prometheus"
 
+	"github.com/percona/rds_exporter/config"
 	"github.com/percona/rds_exporter/sessions"
+	"github.com/percona/rds_exporter/status"
 )
 
 // scraper retrieves metrics from several RDS instances sharing a single session.
@@ -21,12 +24,16 @@ type scraper struct {
 	logStreamNames []string
 	svc            *cloudwatchlogs.CloudWatchLogs
 	nextStartTime  time.Time
+	lastTimes      map[string]time.Time // ResourceID -> timestamp of the latest event seen
+	status         *status.Tracker      // may be nil
+	config         config.Enhanced
+	counters       map[string]*counters // ResourceID -> synthesized counters
 	logger         log.Logger
 
 	testDisallowUnknownFields bool // for tests only
 }
 
-func newScraper(session *session.Session, instances []sessions.Instance, logger log.Logger) *scraper {
+func newScraper(session *session.Session, instances []sessions.Instance, cfg config.Enhanced, status *status.Tracker, logger log.Logger) *scraper {
 	logStreamNames := make([]string, 0, len(instances))
 	for _, instance := range instances {
 		logStreamNames = append(logStreamNames, instance.ResourceID)
@@ -37,12 +44,25 @@ func newScraper(session *session.Session, instances []sessions.Instance, logger
 		logStreamNames: logStreamNames,
 		svc:            cloudwatchlogs.New(session),
 		nextStartTime:  time.Now().Add(-3 * time.Minute).Round(0), // strip monotonic clock reading
+		lastTimes:      make(map[string]time.Time),
+		status:         status,
+		config:         cfg,
+		counters:       make(map[string]*counters),
 		logger:         log.With(logger, "component", "enhanced"),
 	}
 }
 
+// scrapeResult contains the result of a single scrape.
+type scrapeResult struct {
+	metrics  map[string][]prometheus.Metric // ResourceID -> metrics of the latest event
+	messages map[string]string              // ResourceID -> message of the latest event
+	times    map[string]time.Time           // ResourceID -> timestamp of the latest event
+	labels   map[string]prometheus.Labels   // ResourceID -> constant labels of instance's metrics
+	events   map[string][]event             // ResourceID -> all events in timestamp order, for buffered mode and window stats only
+}
+
 // start scrapes metrics in loop and sends them to the channel until context is canceled.
-func (s *scraper) start(ctx context.Context, interval time.Duration, ch chan<- map[string][]prometheus.Metric) {
+func (s *scraper) start(ctx context.Context, interval time.Duration, ch chan<- *scrapeResult) {
 	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
@@ -55,17 +75,20 @@ func (s *scraper) start(ctx context.Context, interval time.Duration, ch chan<- m
 		}
 
 		scrapeCtx, cancel := context.WithTimeout(ctx, interval)
-		m, _ := s.scrape(scrapeCtx)
+		res := s.scrape(scrapeCtx)
 		cancel()
-		ch <- m
+		ch <- res
 	}
 }
 
 // scrape performs a single scrape.
-func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, map[string]string) {
+func (s *scraper) scrape(ctx context.Context) *scrapeResult {
 
 	allMetrics := make(map[string]map[time.Time][]prometheus.Metric) // ResourceID -> event timestamp -> metrics
 	allMessages := make(map[string]map[time.Time]string)             // ResourceID -> event timestamp -> message
+	allOSMetrics := make(map[string]map[time.Time]*osMetrics)        // ResourceID -> event timestamp -> parsed message
+	instances := make(map[string]*sessions.Instance)                 // ResourceID -> instance
+	failed := make(map[string]struct{})                              // ResourceID
 
 	// LogStreamNames parameter supports up to 100 items.
 	// https://docs.aws.amazon.com/AmazonCloudWatchLogs/latest/APIReference/API_FilterLogEvents.html
@@ -113,7 +136,7 @@ func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, m
 				l = log.With(l, "region", instance.Region, "instance", instance.Instance)
 
 				// l.Debugf("Message:\n%s", *event.Message)
-				osMetrics, err := parseOSMetrics([]byte(*event.Message), s.testDisallowUnknownFields)
+				m, err := parseOSMetrics([]byte(*event.Message), s.testDisallowUnknownFields)
 				if err != nil {
 					// only for tests
 					if s.testDisallowUnknownFields {
@@ -121,30 +144,61 @@ func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, m
 					}
 
 					level.Error(l).Log("msg", "Failed to parse metrics.", "error", err)
+					failed[instance.ResourceID] = struct{}{}
+					s.status.Error(instance.Region, instance.Instance, "", err)
 					continue
 				}
-				// l.Debugf("OS Metrics:\n%#v", osMetrics)
+				// l.Debugf("OS Metrics:\n%#v", m)
 
 				timestamp := aws.MillisecondsTimeValue(event.Timestamp).UTC()
-				level.Debug(l).Log("msg", fmt.Sprintf("Timestamp from message: %s; from event: %s.", osMetrics.Timestamp.UTC(), timestamp))
+				level.Debug(l).Log("msg", fmt.Sprintf("Timestamp from message: %s; from event: %s.", m.Timestamp.UTC(), timestamp))
 
 				if allMetrics[instance.ResourceID] == nil {
 					allMetrics[instance.ResourceID] = make(map[time.Time][]prometheus.Metric)
 				}
-				allMetrics[instance.ResourceID][timestamp] = osMetrics.makePrometheusMetrics(instance.Region, instance.Labels)
+				allMetrics[instance.ResourceID][timestamp] = m.makePrometheusMetrics(instance.Region, instance.Labels, s.config)
 
 				if allMessages[instance.ResourceID] == nil {
 					allMessages[instance.ResourceID] = make(map[time.Time]string)
 				}
 				allMessages[instance.ResourceID][timestamp] = *event.Message
+
+				if allOSMetrics[instance.ResourceID] == nil {
+					allOSMetrics[instance.ResourceID] = make(map[time.Time]*osMetrics)
+				}
+				allOSMetrics[instance.ResourceID][timestamp] = m
+				instances[instance.ResourceID] = instance
 			}
 
 			return true // continue pagination
 		}
 		if err := s.svc.FilterLogEventsPagesWithContext(ctx, input, collectAllMetrics); err != nil {
 			level.Error(s.logger).Log("msg", "Failed to filter log events.", "error", err)
+			for _, instance := range s.instances[sliceStart:sliceEnd] {
+				failed[instance.ResourceID] = struct{}{}
+				s.status.Error(instance.Region, instance.Instance, "", err)
+			}
+		}
+	}
+	for _, instance := range s.instances {
+		// StartTime is inclusive, so the latest event of a stalled stream is returned again by every request
+		var fresh bool
+		for timestamp := range allMetrics[instance.ResourceID] {
+			if timestamp.After(s.lastTimes[instance.ResourceID]) {
+				s.lastTimes[instance.ResourceID] = timestamp
+				fresh = true
+			}
 		}
+
+		_, f := failed[instance.ResourceID]
+		if !fresh && !f {
+			// no new events is not a failure of the request, but the scrape did not get any data either
+			f = true
+			s.status.Error(instance.Region, instance.Instance, "", status.ErrNoData)
+		}
+		s.status.Done(instance.Region, instance.Instance, !f)
 	}
+
 	// get better times
 	allTimes := make(map[string][]time.Time)
 	for resourceID, events := range allMetrics {
@@ -156,14 +210,76 @@ func (s *scraper) scrape(ctx context.Context) (map[string][]prometheus.Metric, m
 	var times map[string]time.Time
 	times, s.nextStartTime = betterTimes(allTimes)
 
-	// return only latest metrics/messages
-	resMetrics := make(map[string][]prometheus.Metric) // ResourceID -> metrics
-	resMessages := make(map[string]string)             // ResourceID -> message
+	// return only latest metrics/messages, and all events for buffered mode
+	res := &scrapeResult{
+		metrics:  make(map[string][]prometheus.Metric),
+		messages: make(map[string]string),
+		times:    times,
+		labels:   make(map[string]prometheus.Labels),
+		events:   make(map[string][]event),
+	}
 	for resourceID, timestamp := range times {
-		resMetrics[resourceID] = allMetrics[resourceID][timestamp]
-		resMessages[resourceID] = allMessages[resourceID][timestamp]
+		instance := instances[resourceID]
+		res.messages[resourceID] = allMessages[resourceID][timestamp]
+		res.labels[resourceID] = allOSMetrics[resourceID][timestamp].makeConstLabels(instance.Region, instance.Labels)
+
+		c := s.counters[resourceID]
+		if c == nil && s.config.SynthesizeCounters {
+			c = newCounters()
+			s.counters[resourceID] = c
+		}
+
+		events := allTimes[resourceID]
+		sort.Slice(events, func(i, j int) bool { return events[i].Before(events[j]) })
+		for _, t := range events {
+			if c != nil {
+				c.add(allOSMetrics[resourceID][t], t)
+			}
+			keepEvents := s.config.Buffer > 0 || len(s.config.WindowStats.Metrics) > 0
+			if t != timestamp && !keepEvents {
+				continue
+			}
+
+			metrics := allMetrics[resourceID][t]
+			if c != nil {
+				metrics = append(metrics[:len(metrics):len(metrics)], c.metrics(res.labels[resourceID])...)
+			}
+
+			switch s.timestamps() {
+			case "event":
+				metrics = withTimestamp(metrics, t)
+			case "message":
+				metrics = withTimestamp(metrics, allOSMetrics[resourceID][t].Timestamp)
+			}
+
+			if keepEvents {
+				res.events[resourceID] = append(res.events[resourceID], event{time: t, metrics: metrics})
+			}
+			if t == timestamp {
+				res.metrics[resourceID] = metrics
+			}
+		}
+	}
+
+	return res
+}
+
+// timestamps returns effective enhanced.timestamps configuration value.
+func (s *scraper) timestamps() string {
+	// buffered events are useless without timestamps
+	if s.config.Buffer > 0 && (s.config.Timestamps == "" || s.config.Timestamps == "none") {
+		return "event"
+	}
+	return s.config.Timestamps
+}
+
+// withTimestamp returns metrics with explicit timestamp.
+func withTimestamp(metrics []prometheus.Metric, t time.Time) []prometheus.Metric {
+	res := make([]prometheus.Metric, len(metrics))
+	for i, m := range metrics {
+		res[i] = prometheus.NewMetricWithTimestamp(t, m)
 	}
-	return resMetrics, resMessages
+	return res
 }
 
 // betterTimes returns timestamps of the latest metrics, and also StarTime that should be used in the next request
@@ -8,14 +8,18 @@ import (
 	"testing"
 	"time"
 
+	"github.com/aws/aws-sdk-go/aws"
 	"github.com/percona/exporter_shared/helpers"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
 	"github.com/prometheus/common/promlog"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/percona/rds_exporter/client"
+	"github.com/percona/rds_exporter/client/clienttest"
 	"github.com/percona/rds_exporter/config"
 	"github.com/percona/rds_exporter/sessions"
+	"github.com/percona/rds_exporter/status"
 )
 
 func filterMetrics(metrics []*helpers.Metric) []*helpers.Metric {
@@ -42,17 +46,17 @@ func TestScraper(t *testing.T) {
 	cfg, err := config.Load("../config.tests.yml")
 	require.NoError(t, err)
 	logger := promlog.New(&promlog.Config{})
-	client := client.New(logger)
-	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, false)
+	sess, err := sessions.New(cfg.Instances, clienttest.New(t, logger).HTTP(), logger, false)
 	require.NoError(t, err)
 
 	for session, instances := range sess.AllSessions() {
 		session, instances := session, instances
 		t.Run(fmt.Sprint(instances), func(t *testing.T) {
 			// test that there are no new metrics
-			s := newScraper(session, instances, logger)
+			s := newScraper(session, instances, config.Enhanced{}, nil, logger)
 			s.testDisallowUnknownFields = true
-			metrics, messages := s.scrape(context.Background())
+			res := s.scrape(context.Background())
+			metrics, messages := res.metrics, res.messages
 			require.Len(t, metrics, len(instances))
 			require.Len(t, messages, len(instances))
 
@@ -74,7 +78,7 @@ func TestScraper(t *testing.T) {
 
 				osMetrics, err := parseOSMetrics(readTestDataJSON(t, instanceName), true)
 				require.NoError(t, err)
-				expectedMetrics := helpers.ReadMetrics(osMetrics.makePrometheusMetrics(instance.Region, nil))
+				expectedMetrics := helpers.ReadMetrics(osMetrics.makePrometheusMetrics(instance.Region, nil, config.Enhanced{}))
 				sort.Slice(expectedMetrics, func(i, j int) bool { return expectedMetrics[i].Less(expectedMetrics[j]) })
 				expectedMetrics = filterMetrics(expectedMetrics)
 				expectedLines := helpers.Format(helpers.WriteMetrics(expectedMetrics))
@@ -142,7 +146,7 @@ func TestScraperDisableEnhancedMetrics(t *testing.T) {
 	cfg, err := config.Load("../config.tests.yml")
 	require.NoError(t, err)
 	logger := promlog.New(&promlog.Config{})
-	client := client.New(logger)
+	client := clienttest.New(t, logger)
 	for i := range cfg.Instances {
 		// Disable enhanced metrics in even instances.
 		// This disable instance: no-such-instance.
@@ -165,9 +169,9 @@ func TestScraperDisableEnhancedMetrics(t *testing.T) {
 	for session, instances := range sess.AllSessions() {
 		session, instances := session, instances
 		t.Run(fmt.Sprint(instances), func(t *testing.T) {
-			s := newScraper(session, instances, logger)
+			s := newScraper(session, instances, config.Enhanced{}, nil, logger)
 			s.testDisallowUnknownFields = true
-			metrics, _ := s.scrape(context.Background())
+			metrics := s.scrape(context.Background()).metrics
 
 			for _, instance := range instances {
 				actualMetrics := helpers.ReadMetrics(metrics[instance.ResourceID])
@@ -182,3 +186,82 @@ func TestScraperDisableEnhancedMetrics(t *testing.T) {
 		})
 	}
 }
+
+func TestScraperTimestamps(t *testing.T) {
+	// message timestamp is 2020-12-06T10:34:00Z; event timestamp is a bit later
+	eventTime := time.Date(2020, 12, 6, 10, 34, 5, 0, time.UTC)
+	messageTime := time.Date(2020, 12, 6, 10, 34, 0, 0, time.UTC)
+	sess := newFakeLogs(t, []fakeLogEvent{{
+		EventID:       "1",
+		LogStreamName: "db-QXZYJIL5GR3CBQ4XNCYU2AI5PE",
+		Timestamp:     aws.TimeUnixMilli(eventTime),
+		IngestionTime: aws.TimeUnixMilli(eventTime.Add(time.Second)),
+		Message:       string(readTestDataJSON(t, "mysql-57")),
+	}})
+	instances := []sessions.Instance{{Region: "us-west-2", Instance: "autotest-mysql-57", ResourceID: "db-QXZYJIL5GR3CBQ4XNCYU2AI5PE"}}
+
+	for _, tc := range []struct {
+		cfg      config.Enhanced
+		expected *time.Time
+	}{
+		{config.Enhanced{}, nil},
+		{config.Enhanced{Timestamps: "none"}, nil},
+		{config.Enhanced{Timestamps: "event"}, &eventTime},
+		{config.Enhanced{Timestamps: "message"}, &messageTime},
+		{config.Enhanced{Buffer: time.Minute}, &eventTime},
+	} {
+		tc := tc
+		t.Run(fmt.Sprintf("%q/%s", tc.cfg.Timestamps, tc.cfg.Buffer), func(t *testing.T) {
+			s := newScraper(sess, instances, tc.cfg, nil, promlog.New(&promlog.Config{}))
+			res := s.scrape(context.Background())
+			metrics := res.metrics["db-QXZYJIL5GR3CBQ4XNCYU2AI5PE"]
+			require.NotEmpty(t, metrics)
+
+			for _, m := range metrics {
+				var pb dto.Metric
+				require.NoError(t, m.Write(&pb))
+				if tc.expected == nil {
+					assert.Nil(t, pb.TimestampMs, "%s", m.Desc())
+					continue
+				}
+				assert.Equal(t, aws.TimeUnixMilli(*tc.expected), pb.GetTimestampMs(), "%s", m.Desc())
+			}
+		})
+	}
+}
+
+func TestScraperNoData(t *testing.T) {
+	eventTime := time.Date(2020, 12, 6, 10, 34, 5, 0, time.UTC)
+	sess := newFakeLogs(t, []fakeLogEvent{{
+		EventID:       "1",
+		LogStreamName: "db-QXZYJIL5GR3CBQ4XNCYU2AI5PE",
+		Timestamp:     aws.TimeUnixMilli(eventTime),
+		IngestionTime: aws.TimeUnixMilli(eventTime.Add(time.Second)),
+		Message:       string(readTestDataJSON(t, "mysql-57")),
+	}})
+	instances := []sessions.Instance{{Region: "us-west-2", Instance: "autotest-mysql-57", ResourceID: "db-QXZYJIL5GR3CBQ4XNCYU2AI5PE"}}
+	tracker := status.New("enhanced")
+	s := newScraper(sess, instances, config.Enhanced{}, tracker, promlog.New(&promlog.Config{}))
+
+	// the same event is returned twice, like the latest event of a stalled stream
+	s.scrape(context.Background())
+	expected := `
+# HELP rds_exporter_scrape_success Whether the last scrape of instance's metrics was successful (1) or not (0).
+# TYPE rds_exporter_scrape_success gauge
+rds_exporter_scrape_success{instance="autotest-mysql-57",region="us-west-2",source="enhanced"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(tracker, strings.NewReader(expected),
+		"rds_exporter_scrape_errors_total", "rds_exporter_scrape_success"))
+
+	s.scrape(context.Background())
+	expected = `
+# HELP rds_exporter_scrape_errors_total Total number of errors during scrapes of instance's metrics by metric (empty for all metrics) and error code.
+# TYPE rds_exporter_scrape_errors_total counter
+rds_exporter_scrape_errors_total{code="NoData",instance="autotest-mysql-57",metric="",region="us-west-2",source="enhanced"} 1
+# HELP rds_exporter_scrape_success Whether the last scrape of instance's metrics was successful (1) or not (0).
+# TYPE rds_exporter_scrape_success gauge
+rds_exporter_scrape_success{instance="autotest-mysql-57",region="us-west-2",source="enhanced"} 0
+`
+	assert.NoError(t, testutil.CollectAndCompare(tracker, strings.NewReader(expected),
+		"rds_exporter_scrape_errors_total", "rds_exporter_scrape_success"))
+}
@@ -0,0 +1,195 @@
+package enhanced
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log/level"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+)
+
+// staleWindow is the time without events after which instance's window is removed.
+const staleWindow = 10 * maxInterval
+
+// windowSeries contains values of a single gauge received since the last scrape.
+type windowSeries struct {
+	name   string
+	help   string
+	labels prometheus.Labels
+	values []float64
+}
+
+// window accumulates values of gauges of a single instance for statistics.
+type window struct {
+	last   time.Time                // timestamp of the last added event
+	series map[string]*windowSeries // series key -> series
+	stats  []prometheus.Metric      // statistics computed by the last scrape
+}
+
+// windowSamples returns gauges from given metrics with names matching prefixes.
+// Metrics are gathered by a separate registry to get their names, help, labels, and values.
+func windowSamples(metrics []prometheus.Metric, prefixes []string) ([]*dto.MetricFamily, error) {
+	r := prometheus.NewRegistry()
+	if err := r.Register(metricsCollector(metrics)); err != nil {
+		return nil, err
+	}
+	mfs, err := r.Gather()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*dto.MetricFamily, 0, len(mfs))
+	for _, mf := range mfs {
+		if mf.GetType() != dto.MetricType_GAUGE {
+			continue
+		}
+		for _, p := range prefixes {
+			if strings.HasPrefix(mf.GetName(), p) {
+				res = append(res, mf)
+				break
+			}
+		}
+	}
+	return res, nil
+}
+
+// add adds gauges values from event's metric families.
+func (w *window) add(mfs []*dto.MetricFamily) {
+	for _, mf := range mfs {
+		for _, m := range mf.Metric {
+			key := mf.GetName() + "\xfd" + labelsKey(m)
+			s := w.series[key]
+			if s == nil {
+				labels := make(prometheus.Labels, len(m.Label))
+				for _, l := range m.Label {
+					labels[l.GetName()] = l.GetValue()
+				}
+				s = &windowSeries{
+					name:   mf.GetName(),
+					help:   mf.GetHelp(),
+					labels: labels,
+				}
+				w.series[key] = s
+			}
+			s.values = append(s.values, m.GetGauge().GetValue())
+		}
+	}
+}
+
+// update replaces statistics with ones for values received since the last scrape, and resets values.
+// Previous statistics are kept if there are no values.
+func (w *window) update(quantiles []float64) {
+	keys := make([]string, 0, len(w.series))
+	for k, s := range w.series {
+		if len(s.values) > 0 {
+			keys = append(keys, k)
+		}
+	}
+	if len(keys) == 0 {
+		return
+	}
+	sort.Strings(keys)
+
+	w.stats = make([]prometheus.Metric, 0, len(keys)*(3+len(quantiles)))
+	for _, k := range keys {
+		s := w.series[k]
+		values := s.values
+		sort.Float64s(values)
+
+		var sum float64
+		for _, v := range values {
+			sum += v
+		}
+
+		gauge := func(suffix, help string, value float64) {
+			desc := prometheus.NewDesc(s.name+"_"+suffix, s.help+" "+help, nil, s.labels)
+			w.stats = append(w.stats, prometheus.MustNewConstMetric(desc, prometheus.GaugeValue, value))
+		}
+		gauge("min", "Minimum since the last scrape.", values[0])
+		gauge("max", "Maximum since the last scrape.", values[len(values)-1])
+		gauge("mean", "Mean since the last scrape.", sum/float64(len(values)))
+		for _, q := range quantiles {
+			gauge(quantileSuffix(q), "Quantile "+strconv.FormatFloat(q, 'f', -1, 64)+" since the last scrape.", quantile(values, q))
+		}
+	}
+
+	// remove series without values: they may belong to processes that do not exist anymore
+	for k, s := range w.series {
+		if len(s.values) == 0 {
+			delete(w.series, k)
+			continue
+		}
+		s.values = s.values[:0]
+	}
+}
+
+// quantileSuffix returns metric name suffix for quantile: p95 for 0.95, p99_9 for 0.999.
+func quantileSuffix(q float64) string {
+	return "p" + strings.ReplaceAll(strconv.FormatFloat(q*100, 'f', -1, 64), ".", "_")
+}
+
+// quantile returns q-quantile of sorted values using linear interpolation between closest ranks.
+func quantile(sorted []float64, q float64) float64 {
+	pos := q * float64(len(sorted)-1)
+	i := int(math.Floor(pos))
+	if i+1 >= len(sorted) {
+		return sorted[len(sorted)-1]
+	}
+	return sorted[i] + (pos-float64(i))*(sorted[i+1]-sorted[i])
+}
+
+// addWindowEvents adds new events to instances' windows.
+// Windows of instances without events for a long time are removed.
+// It should be called with write lock held.
+func (c *Collector) addWindowEvents(res *scrapeResult, now time.Time) {
+	prefixes := c.config.WindowStats.Metrics
+	if len(prefixes) == 0 {
+		return
+	}
+
+	for id, events := range res.events {
+		w := c.windows[id]
+		if w == nil {
+			w = &window{series: make(map[string]*windowSeries)}
+			c.windows[id] = w
+		}
+
+		for _, e := range events {
+			// the latest event is returned by FilterLogEvents again
+			if !e.time.After(w.last) {
+				continue
+			}
+			w.last = e.time
+
+			mfs, err := windowSamples(e.metrics, prefixes)
+			if err != nil {
+				level.Error(c.logger).Log("msg", "Failed to gather metrics for window statistics.", "error", err)
+				continue
+			}
+			w.add(mfs)
+		}
+	}
+
+	for id, w := range c.windows {
+		if now.Sub(w.last) > staleWindow {
+			delete(c.windows, id)
+		}
+	}
+}
+
+// updateWindowStats updates window statistics of all instances with values received since the last scrape.
+func (c *Collector) updateWindowStats() {
+	if len(c.config.WindowStats.Metrics) == 0 {
+		return
+	}
+
+	c.rw.Lock()
+	for _, w := range c.windows {
+		w.update(c.config.WindowStats.Quantiles)
+	}
+	c.rw.Unlock()
+}
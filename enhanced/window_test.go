@@ -0,0 +1,108 @@
+package enhanced
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/percona/exporter_shared/helpers"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/percona/rds_exporter/config"
+	"github.com/percona/rds_exporter/status"
+)
+
+func TestQuantile(t *testing.T) {
+	values := []float64{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11}
+	assert.Equal(t, 1.0, quantile(values, 0))
+	assert.Equal(t, 6.0, quantile(values, 0.5))
+	assert.InDelta(t, 10.5, quantile(values, 0.95), 1e-9)
+	assert.Equal(t, 11.0, quantile(values, 1))
+	assert.Equal(t, 42.0, quantile([]float64{42}, 0.99))
+
+	assert.Equal(t, "p50", quantileSuffix(0.5))
+	assert.Equal(t, "p95", quantileSuffix(0.95))
+	assert.Equal(t, "p99_9", quantileSuffix(0.999))
+}
+
+func TestCollectorWindowStats(t *testing.T) {
+	cpu := prometheus.NewDesc("rdsosmetrics_cpuUtilization_total", "The total percentage of the CPU in use.", nil, prometheus.Labels{"instance": "db"})
+	vcpus := prometheus.NewDesc("rdsosmetrics_General_numVCPUs", "The number of virtual CPUs for the DB instance.", nil, prometheus.Labels{"instance": "db"})
+	start := time.Now().Add(-time.Minute)
+	var events []event
+	for _, e := range []struct {
+		seconds int
+		value   float64
+	}{
+		{1, 10}, {3, 90}, {5, 20}, // before the first scrape
+		{11, 40}, // before the second scrape
+		{21, 60}, // before the third scrape
+	} {
+		events = append(events, event{
+			time: start.Add(time.Duration(e.seconds) * time.Second),
+			metrics: []prometheus.Metric{
+				prometheus.MustNewConstMetric(cpu, prometheus.GaugeValue, e.value),
+				prometheus.MustNewConstMetric(vcpus, prometheus.GaugeValue, 2),
+			},
+		})
+	}
+
+	c := &Collector{
+		config: config.Enhanced{WindowStats: config.WindowStats{
+			Metrics:   []string{"rdsosmetrics_cpuUtilization_"},
+			Quantiles: []float64{0.5},
+		}},
+		status:  status.New("enhanced"),
+		metrics: make(map[string][]prometheus.Metric),
+		times:   make(map[string]time.Time),
+		labels:  make(map[string]prometheus.Labels),
+		events:  make(map[string][]event),
+		windows: make(map[string]*window),
+	}
+	stats := func() map[string]float64 {
+		res := make(map[string]float64)
+		for _, m := range helpers.ReadMetrics(helpers.CollectMetrics(c)) {
+			if strings.HasPrefix(m.Name, "rdsosmetrics_cpuUtilization_total_") {
+				res[m.Name] = m.Value
+			}
+		}
+		return res
+	}
+	set := func(events []event) {
+		latest := events[len(events)-1]
+		c.setMetrics(&scrapeResult{
+			metrics: map[string][]prometheus.Metric{"db": latest.metrics},
+			times:   map[string]time.Time{"db": latest.time},
+			labels:  map[string]prometheus.Labels{"db": {"instance": "db"}},
+			events:  map[string][]event{"db": events},
+		})
+	}
+
+	assert.Empty(t, stats(), "statistics should not be exposed without events")
+
+	set(events[:3])
+	expected := map[string]float64{
+		"rdsosmetrics_cpuUtilization_total_min":  10,
+		"rdsosmetrics_cpuUtilization_total_max":  90,
+		"rdsosmetrics_cpuUtilization_total_mean": 40,
+		"rdsosmetrics_cpuUtilization_total_p50":  20,
+	}
+	assert.Equal(t, expected, stats())
+	assert.Equal(t, expected, stats(), "statistics should be kept without new events")
+
+	set(events[2:4]) // the latest event is returned again
+	assert.Equal(t, map[string]float64{
+		"rdsosmetrics_cpuUtilization_total_min":  40,
+		"rdsosmetrics_cpuUtilization_total_max":  40,
+		"rdsosmetrics_cpuUtilization_total_mean": 40,
+		"rdsosmetrics_cpuUtilization_total_p50":  40,
+	}, stats(), "statistics should include only events since the last scrape")
+
+	set(events[4:])
+	assert.Equal(t, 60.0, stats()["rdsosmetrics_cpuUtilization_total_max"])
+
+	// windows of instances without events are removed
+	c.addWindowEvents(&scrapeResult{}, events[4].time.Add(staleWindow+time.Second))
+	assert.Empty(t, c.windows)
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
@@ -26,8 +30,12 @@ var (
 	listenAddressF       = kingpin.Flag("web.listen-address", "Address on which to expose metrics and web interface.").Default(":9040").String()
 	basicMetricsPathF    = kingpin.Flag("web.basic-telemetry-path", "Path under which to expose exporter's basic metrics.").Default("/basic").String()
 	enhancedMetricsPathF = kingpin.Flag("web.enhanced-telemetry-path", "Path under which to expose exporter's enhanced metrics.").Default("/enhanced").String()
+	tracePathF           = kingpin.Flag("web.trace-path", "Path under which to show and switch AWS requests tracing.").Default("/trace").String()
 	configFileF          = kingpin.Flag("config.file", "Path to configuration file.").Default("config.yml").String()
-	logTraceF            = kingpin.Flag("log.trace", "Enable verbose tracing of AWS requests (will log credentials).").Default("false").Bool()
+	traceSwitchF         = kingpin.Flag("web.enable-trace-switch", "Allow switching AWS requests tracing with POST requests to trace path.").Default("false").Bool()
+	logTraceF            = kingpin.Flag("log.trace", "Enable tracing of AWS requests for all instances (secrets are redacted).").Default("false").Bool()
+	cassetteRecordF      = kingpin.Flag("cassette.record", "Path to cassette file to record sanitized AWS API interactions to.").String()
+	cassetteReplayF      = kingpin.Flag("cassette.replay", "Path to cassette file to replay AWS API interactions from instead of using network.").String()
 	logger               = log.NewNopLogger()
 )
 
@@ -48,15 +56,36 @@ func main() {
 	}
 
 	client := client.New(logger)
+	switch {
+	case *cassetteRecordF != "" && *cassetteReplayF != "":
+		level.Error(logger).Log("msg", "Can't both record and replay AWS API interactions.")
+		os.Exit(1)
+	case *cassetteRecordF != "":
+		if err = client.Record(*cassetteRecordF); err != nil {
+			level.Error(logger).Log("msg", "Can't create cassette file", "error", err)
+			os.Exit(1)
+		}
+		level.Info(logger).Log("msg", fmt.Sprintf("Recording AWS API interactions to %s.", *cassetteRecordF))
+	case *cassetteReplayF != "":
+		if err = client.Replay(*cassetteReplayF); err != nil {
+			level.Error(logger).Log("msg", "Can't read cassette file", "error", err)
+			os.Exit(1)
+		}
+		level.Info(logger).Log("msg", fmt.Sprintf("Replaying AWS API interactions from %s.", *cassetteReplayF))
+	}
 	sess, err := sessions.New(cfg.Instances, client.HTTP(), logger, *logTraceF)
 	if err != nil {
 		level.Error(logger).Log("msg", "Can't create sessions", "error", err)
 		os.Exit(1)
 	}
 
+	// canceled on signal to stop background scraping and serving
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
 	// basic metrics + client metrics + exporter own metrics (ProcessCollector and GoCollector)
 	{
-		prometheus.MustRegister(basic.New(cfg, sess, logger))
+		prometheus.MustRegister(basic.New(ctx, cfg, sess, logger))
 		prometheus.MustRegister(client)
 		http.Handle(*basicMetricsPathF, promhttp.HandlerFor(prometheus.DefaultGatherer, promhttp.HandlerOpts{
 			//ErrorLog:      log.NewErrorLogger(), TODO TS
@@ -67,15 +96,36 @@ func main() {
 	// enhanced metrics
 	{
 		registry := prometheus.NewRegistry()
-		registry.MustRegister(enhanced.NewCollector(sess, logger))
-		http.Handle(*enhancedMetricsPathF, promhttp.HandlerFor(registry, promhttp.HandlerOpts{
+		collector := enhanced.NewCollector(sess, cfg.Enhanced, logger)
+		registry.MustRegister(collector)
+		http.Handle(*enhancedMetricsPathF, promhttp.HandlerFor(collector.Gatherer(registry), promhttp.HandlerOpts{
 			//ErrorLog:      log.NewErrorLogger(), TODO TS
 			ErrorHandling: promhttp.ContinueOnError,
+			// buffered events have timestamps; OpenMetrics allows several samples of the same series
+			EnableOpenMetrics: cfg.Enhanced.Buffer > 0,
 		}))
 	}
 
+	http.Handle(*tracePathF, sess.TraceHandler(*traceSwitchF))
+
 	level.Info(logger).Log("msg", fmt.Sprintf("Basic metrics   : http://%s%s", *listenAddressF, *basicMetricsPathF))
 	level.Info(logger).Log("msg", fmt.Sprintf("Enhanced metrics: http://%s%s", *listenAddressF, *enhancedMetricsPathF))
+	level.Info(logger).Log("msg", fmt.Sprintf("AWS tracing     : http://%s%s", *listenAddressF, *tracePathF))
 
-	level.Error(logger).Log("error", http.ListenAndServe(*listenAddressF, nil))
+	// stop serving on signal, then flush and close the cassette file
+	srv := &http.Server{Addr: *listenAddressF} //nolint:gosec
+	go func() {
+		<-ctx.Done()
+		level.Info(logger).Log("msg", "Shutting down...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = srv.Shutdown(shutdownCtx)
+	}()
+
+	if err = srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+		level.Error(logger).Log("error", err)
+	}
+	if err = client.Close(); err != nil {
+		level.Error(logger).Log("msg", "Can't close cassette file", "error", err)
+	}
 }
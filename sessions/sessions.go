@@ -25,8 +25,30 @@ type Instance struct {
 	DisableBasicMetrics        bool
 	DisableEnhancedMetrics     bool
 	ResourceID                 string
+	Engine                     string // like "mysql" or "aurora-postgresql"; empty if unknown
 	Labels                     map[string]string
 	EnhancedMonitoringInterval time.Duration
+
+	// override Basic settings; may be empty
+	BasicPeriod   time.Duration
+	BasicDelay    time.Duration
+	BasicRange    time.Duration
+	BasicAdaptive *bool
+}
+
+// newInstance returns runtime instance information for given configuration.
+func newInstance(instance config.Instance) Instance {
+	return Instance{
+		Region:                 instance.Region,
+		Instance:               instance.Instance,
+		Labels:                 instance.Labels,
+		DisableBasicMetrics:    instance.DisableBasicMetrics,
+		DisableEnhancedMetrics: instance.DisableEnhancedMetrics,
+		BasicPeriod:            instance.BasicPeriod,
+		BasicDelay:             instance.BasicDelay,
+		BasicRange:             instance.BasicRange,
+		BasicAdaptive:          instance.BasicAdaptive,
+	}
 }
 
 func (i Instance) String() string {
@@ -41,34 +63,34 @@ func (i Instance) String() string {
 // Sessions is a pool of AWS sessions.
 type Sessions struct {
 	sessions map[*session.Session][]Instance
+	tracers  map[*session.Session]*tracer
 }
 
 // New creates a new sessions pool for given configuration.
+// If trace is true, AWS requests tracing is enabled for all sessions.
 func New(instances []config.Instance, client *http.Client, logger log.Logger, trace bool) (*Sessions, error) {
 	logger = log.With(logger, "component", "sessions")
 	level.Info(logger).Log("msg", "Creating sessions...")
 	res := &Sessions{
 		sessions: make(map[*session.Session][]Instance),
+		tracers:  make(map[*session.Session]*tracer),
 	}
 
 	sharedSessions := make(map[string]*session.Session) // region/key => session
 	for _, instance := range instances {
 		// re-use session for the same region and key (explicit or empty for implicit) pair
 		if s := sharedSessions[instance.Region+"/"+instance.AWSAccessKey]; s != nil {
-			res.sessions[s] = append(res.sessions[s], Instance{
-				Region:                 instance.Region,
-				Instance:               instance.Instance,
-				Labels:                 instance.Labels,
-				DisableBasicMetrics:    instance.DisableBasicMetrics,
-				DisableEnhancedMetrics: instance.DisableEnhancedMetrics,
-			})
+			if instance.Trace {
+				res.tracers[s].setEnabled(true)
+			}
+			res.sessions[s] = append(res.sessions[s], newInstance(instance))
 			continue
 		}
 
 		// use given credentials, or default credential chain
 		var creds *credentials.Credentials
 
-		creds, err := buildCredentials(instance)
+		creds, err := buildCredentials(instance, client)
 
 		if err != nil {
 			return nil, err
@@ -81,18 +103,7 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 			HTTPClient:  client,
 		}
 		if trace {
-			// fail-safe
-			if _, ok := os.LookupEnv("CI"); ok {
-				panic("Do not enable AWS request tracing on CI - output will contain credentials.")
-			}
-
-			awsCfg.Logger = aws.LoggerFunc(func(args ...interface{}) {
-				level.Debug(logger).Log("msg", args)
-			})
 			awsCfg.CredentialsChainVerboseErrors = aws.Bool(true)
-			level := aws.LogDebugWithSigning | aws.LogDebugWithHTTPBody
-			level |= aws.LogDebugWithRequestRetries | aws.LogDebugWithRequestErrors | aws.LogDebugWithEventStreamBody
-			awsCfg.LogLevel = aws.LogLevel(level)
 		}
 
 		// store session
@@ -100,14 +111,14 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 		if err != nil {
 			return nil, err
 		}
+		t := &tracer{
+			logger: log.With(logger, "region", instance.Region),
+		}
+		t.setEnabled(trace || instance.Trace)
+		s.Handlers.Complete.PushBackNamed(t.handler())
+		res.tracers[s] = t
 		sharedSessions[instance.Region+"/"+instance.AWSAccessKey] = s
-		res.sessions[s] = append(res.sessions[s], Instance{
-			Region:                 instance.Region,
-			Instance:               instance.Instance,
-			Labels:                 instance.Labels,
-			DisableBasicMetrics:    instance.DisableBasicMetrics,
-			DisableEnhancedMetrics: instance.DisableEnhancedMetrics,
-		})
+		res.sessions[s] = append(res.sessions[s], newInstance(instance))
 	}
 
 	// add resource ID to all instances
@@ -127,6 +138,7 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 				for i, instance := range instances {
 					if *dbInstance.DBInstanceIdentifier == instance.Instance {
 						instances[i].ResourceID = *dbInstance.DbiResourceId
+						instances[i].Engine = aws.StringValue(dbInstance.Engine)
 						instances[i].EnhancedMonitoringInterval = time.Duration(*dbInstance.MonitoringInterval) * time.Second
 					}
 				}
@@ -154,6 +166,7 @@ func New(instances []config.Instance, client *http.Client, logger log.Logger, tr
 	for _, s := range sharedSessions {
 		if len(res.sessions[s]) == 0 {
 			delete(res.sessions, s)
+			delete(res.tracers, s)
 		}
 	}
 
@@ -182,11 +195,14 @@ func (s *Sessions) GetSession(region, instance string) (*session.Session, *Insta
 	return nil, nil
 }
 
-func buildCredentials(instance config.Instance) (*credentials.Credentials, error) {
+// buildCredentials returns credentials for given instance, or nil for default credential chain.
+// STS requests for assuming role use given HTTP client, so they are measured, recorded, and replayed like others.
+func buildCredentials(instance config.Instance, client *http.Client) (*credentials.Credentials, error) {
 	if instance.AWSRoleArn != "" {
 		stsSession, err := session.NewSession(&aws.Config{
 			Region:      aws.String(instance.Region),
 			Credentials: credentials.NewStaticCredentials(instance.AWSAccessKey, instance.AWSSecretKey, ""),
+			HTTPClient:  client,
 		})
 		if err != nil {
 			return nil, err
@@ -11,7 +11,7 @@ import (
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
-	"github.com/percona/rds_exporter/client"
+	"github.com/percona/rds_exporter/client/clienttest"
 	"github.com/percona/rds_exporter/config"
 )
 
@@ -23,6 +23,8 @@ var (
 func TestSession(t *testing.T) {
 	cfg, err := config.Load("../config.tests.yml")
 	require.NoError(t, err)
+	logger := promlog.New(&promlog.Config{})
+	client := clienttest.New(t, logger)
 
 	// set explicit keys to first instance to test grouping
 	cfg.Instances[0].AWSAccessKey = os.Getenv("AWS_ACCESS_KEY")
@@ -31,8 +33,6 @@ func TestSession(t *testing.T) {
 		require.Fail(t, "AWS_ACCESS_KEY and AWS_SECRET_KEY environment variables must be set for this test")
 	}
 
-	logger := promlog.New(&promlog.Config{})
-	client := client.New(logger)
 	sessions, err := New(cfg.Instances, client.HTTP(), logger, false)
 	require.NoError(t, err)
 
@@ -59,24 +59,28 @@ func TestSession(t *testing.T) {
 		Region:                     "us-east-1",
 		Instance:                   "autotest-aurora-mysql-56",
 		ResourceID:                 "db-OQT42DPIZWWQBVXQ2LH2BW3SV4",
+		Engine:                     "aurora",
 		EnhancedMonitoringInterval: time.Minute,
 	}
 	p10iExpected := Instance{
 		Region:                     "us-east-1",
 		Instance:                   "autotest-psql-10",
 		ResourceID:                 "db-PUZFCRUUHY365QFJLTOUWRDOCQ",
+		Engine:                     "postgres",
 		EnhancedMonitoringInterval: time.Minute,
 	}
 	m57iExpected := Instance{
 		Region:                     "us-west-2",
 		Instance:                   "autotest-mysql-57",
 		ResourceID:                 "db-QXZYJIL5GR3CBQ4XNCYU2AI5PE",
+		Engine:                     "mysql",
 		EnhancedMonitoringInterval: time.Minute,
 	}
 	ap11iExpected := Instance{
 		Region:                     "us-west-2",
 		Instance:                   "autotest-aurora-psql-11",
 		ResourceID:                 "db-TYM5GWPPEMFCR5L6YX6ZBHUIUE",
+		Engine:                     "aurora-postgresql",
 		EnhancedMonitoringInterval: time.Minute,
 	}
 
@@ -94,3 +98,25 @@ func TestSession(t *testing.T) {
 		// ap11s == m57s
 	}, all)
 }
+
+func TestNewInstance(t *testing.T) {
+	adaptive := true
+	instance := newInstance(config.Instance{
+		Region:              "us-east-1",
+		Instance:            "rds-mysql57",
+		AWSAccessKey:        "AKIA",
+		AWSSecretKey:        "secret",
+		DisableBasicMetrics: true,
+		Labels:              map[string]string{"foo": "bar"},
+		BasicPeriod:         5 * time.Minute,
+		BasicAdaptive:       &adaptive,
+	})
+	assert.Equal(t, Instance{
+		Region:              "us-east-1",
+		Instance:            "rds-mysql57",
+		DisableBasicMetrics: true,
+		Labels:              map[string]string{"foo": "bar"},
+		BasicPeriod:         5 * time.Minute,
+		BasicAdaptive:       &adaptive,
+	}, instance)
+}
@@ -0,0 +1,142 @@
+package sessions
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awsutil"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/go-kit/log"
+	"github.com/go-kit/log/level"
+)
+
+// tracer logs AWS API requests made with a single session, with secrets redacted.
+// It replaces AWS SDK logging that writes request signatures and credentials.
+type tracer struct {
+	enabled int32 // accessed atomically
+	logger  log.Logger
+}
+
+// secretParamRE matches secret parameters in the prettified request parameters.
+var secretParamRE = regexp.MustCompile(`(\b\w*(?:Password|Secret\w*|SessionToken|TokenCode|AccessKeyId|Signature|WebIdentityToken|SAMLAssertion)\s*:\s*)"[^"]*"`)
+
+// redactParams returns single-line prettified request parameters with secrets redacted.
+func redactParams(params interface{}) string {
+	s := secretParamRE.ReplaceAllString(awsutil.Prettify(params), `$1"REDACTED"`)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// setEnabled enables or disables tracing.
+func (t *tracer) setEnabled(enabled bool) {
+	var v int32
+	if enabled {
+		v = 1
+	}
+	atomic.StoreInt32(&t.enabled, v)
+}
+
+// isEnabled returns true if tracing is enabled.
+func (t *tracer) isEnabled() bool {
+	return atomic.LoadInt32(&t.enabled) == 1
+}
+
+// handler returns AWS SDK request handler that should be called on request completion.
+func (t *tracer) handler() request.NamedHandler {
+	return request.NamedHandler{
+		Name: "rds_exporter.tracer",
+		Fn:   t.trace,
+	}
+}
+
+// trace logs completed request.
+func (t *tracer) trace(r *request.Request) {
+	if !t.isEnabled() {
+		return
+	}
+
+	keyvals := []interface{}{
+		"msg", "AWS request",
+		"service", r.ClientInfo.ServiceName,
+		"operation", r.Operation.Name,
+		"params", redactParams(r.Params),
+		"retries", r.RetryCount,
+		"duration", time.Since(r.Time),
+		"last_attempt_duration", time.Since(r.AttemptTime),
+	}
+	if r.HTTPResponse != nil {
+		keyvals = append(keyvals, "status", r.HTTPResponse.StatusCode)
+	}
+	if r.RequestID != "" {
+		keyvals = append(keyvals, "request_id", r.RequestID)
+	}
+	if r.Error != nil {
+		keyvals = append(keyvals, "error", r.Error)
+	}
+	level.Info(t.logger).Log(keyvals...)
+}
+
+// SetTrace enables or disables AWS requests tracing for the session used by given instance.
+// Tracing is enabled or disabled for all sessions if both region and instance are empty.
+// It returns false if instance is not found.
+func (s *Sessions) SetTrace(region, instance string, enabled bool) bool {
+	if region == "" && instance == "" {
+		for _, t := range s.tracers {
+			t.setEnabled(enabled)
+		}
+		return true
+	}
+
+	session, _ := s.GetSession(region, instance)
+	if session == nil {
+		return false
+	}
+	s.tracers[session].setEnabled(enabled)
+	return true
+}
+
+// TraceHandler returns HTTP handler that shows AWS requests tracing status for all instances on GET.
+// If allowSwitch is true, it also enables or disables it on POST with "region", "instance", and "enabled" form values;
+// otherwise, POST is forbidden.
+func (s *Sessions) TraceHandler(allowSwitch bool) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		switch req.Method {
+		case http.MethodGet:
+			// nothing, show status below
+		case http.MethodPost:
+			if !allowSwitch {
+				http.Error(rw, "Switching tracing is disabled.", http.StatusForbidden)
+				return
+			}
+			enabled, err := strconv.ParseBool(req.FormValue("enabled"))
+			if err != nil {
+				http.Error(rw, fmt.Sprintf("Invalid enabled value: %s.", err), http.StatusBadRequest)
+				return
+			}
+			if !s.SetTrace(req.FormValue("region"), req.FormValue("instance"), enabled) {
+				http.Error(rw, "Instance not found.", http.StatusNotFound)
+				return
+			}
+		default:
+			http.Error(rw, "Method not allowed.", http.StatusMethodNotAllowed)
+			return
+		}
+
+		lines := make([]string, 0, len(s.sessions))
+		for session, instances := range s.sessions {
+			enabled := s.tracers[session].isEnabled()
+			for _, instance := range instances {
+				lines = append(lines, fmt.Sprintf("%s\t%t\n", instance, enabled))
+			}
+		}
+		sort.Strings(lines)
+		for _, line := range lines {
+			_, _ = rw.Write([]byte(line))
+		}
+	})
+}
@@ -0,0 +1,96 @@
+package sessions
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/rds"
+	"github.com/aws/aws-sdk-go/service/sts"
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRedactParams(t *testing.T) {
+	assert.Equal(t,
+		`{ DBInstanceIdentifier: "rds-mysql57", MasterUserPassword: "REDACTED" }`,
+		redactParams(&rds.ModifyDBInstanceInput{
+			DBInstanceIdentifier: aws.String("rds-mysql57"),
+			MasterUserPassword:   aws.String("secret"),
+		}),
+	)
+	assert.Equal(t,
+		`{ RoleArn: "arn:aws:iam::76784568345:role/my-role", RoleSessionName: "rds_exporter", SerialNumber: "mfa", TokenCode: "REDACTED" }`,
+		redactParams(&sts.AssumeRoleInput{
+			RoleArn:         aws.String("arn:aws:iam::76784568345:role/my-role"),
+			RoleSessionName: aws.String("rds_exporter"),
+			SerialNumber:    aws.String("mfa"),
+			TokenCode:       aws.String("123456"),
+		}),
+	)
+	assert.Equal(t,
+		`{ RoleArn: "arn", RoleSessionName: "rds_exporter", WebIdentityToken: "REDACTED" }`,
+		redactParams(&sts.AssumeRoleWithWebIdentityInput{
+			RoleArn:          aws.String("arn"),
+			RoleSessionName:  aws.String("rds_exporter"),
+			WebIdentityToken: aws.String("eyJhbGciOi"),
+		}),
+	)
+	assert.Equal(t,
+		`{ PrincipalArn: "principal", RoleArn: "arn", SAMLAssertion: "REDACTED" }`,
+		redactParams(&sts.AssumeRoleWithSAMLInput{
+			PrincipalArn:  aws.String("principal"),
+			RoleArn:       aws.String("arn"),
+			SAMLAssertion: aws.String("PHNhbWw="),
+		}),
+	)
+}
+
+func TestTraceHandler(t *testing.T) {
+	s1, s2 := session.Must(session.NewSession()), session.Must(session.NewSession())
+	sessions := &Sessions{
+		sessions: map[*session.Session][]Instance{
+			s1: {{Region: "us-east-1", Instance: "rds-aurora1"}},
+			s2: {{Region: "us-east-1", Instance: "rds-mysql57"}, {Region: "us-east-1", Instance: "rds-psql10"}},
+		},
+		tracers: map[*session.Session]*tracer{
+			s1: {logger: log.NewNopLogger()},
+			s2: {logger: log.NewNopLogger()},
+		},
+	}
+	h := sessions.TraceHandler(true)
+
+	do := func(method string, form url.Values) (int, string) {
+		req := httptest.NewRequest(method, "/trace?"+form.Encode(), nil)
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code, rec.Body.String()
+	}
+
+	code, body := do(http.MethodGet, nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "us-east-1/rds-aurora1\tfalse\nus-east-1/rds-mysql57\tfalse\nus-east-1/rds-psql10\tfalse\n", body)
+
+	code, body = do(http.MethodPost, url.Values{"region": {"us-east-1"}, "instance": {"rds-psql10"}, "enabled": {"true"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "us-east-1/rds-aurora1\tfalse\nus-east-1/rds-mysql57\ttrue\nus-east-1/rds-psql10\ttrue\n", body)
+
+	code, _ = do(http.MethodPost, url.Values{"region": {"us-east-1"}, "instance": {"no-such-instance"}, "enabled": {"true"}})
+	assert.Equal(t, http.StatusNotFound, code)
+
+	code, body = do(http.MethodPost, url.Values{"enabled": {"true"}})
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "us-east-1/rds-aurora1\ttrue\nus-east-1/rds-mysql57\ttrue\nus-east-1/rds-psql10\ttrue\n", body)
+
+	// read-only handler
+	h = sessions.TraceHandler(false)
+	code, _ = do(http.MethodPost, url.Values{"enabled": {"false"}})
+	assert.Equal(t, http.StatusForbidden, code)
+	code, body = do(http.MethodGet, nil)
+	require.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "us-east-1/rds-aurora1\ttrue\nus-east-1/rds-mysql57\ttrue\nus-east-1/rds-psql10\ttrue\n", body)
+}
@@ -0,0 +1,117 @@
+// Package status tracks per-instance scrape results.
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Tracker tracks scrape results of a single source (basic or enhanced metrics) for all instances.
+// All methods of nil Tracker do nothing.
+type Tracker struct {
+	source string
+
+	mSuccess     *prometheus.GaugeVec
+	mErrors      *prometheus.CounterVec
+	mLastSuccess *prometheus.GaugeVec
+}
+
+// New creates a new Tracker for given source.
+func New(source string) *Tracker {
+	return &Tracker{
+		source: source,
+
+		mSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_exporter_scrape_success",
+			Help: "Whether the last scrape of instance's metrics was successful (1) or not (0).",
+		}, []string{"source", "region", "instance"}),
+		mErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "rds_exporter_scrape_errors_total",
+			Help: "Total number of errors during scrapes of instance's metrics by metric (empty for all metrics) and error code.",
+		}, []string{"source", "region", "instance", "metric", "code"}),
+		mLastSuccess: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "rds_exporter_last_scrape_success_timestamp_seconds",
+			Help: "The time of the last successful scrape of instance's metrics (UNIX seconds).",
+		}, []string{"source", "region", "instance"}),
+	}
+}
+
+// ErrNoData is reported when a scrape returned no data for an instance.
+// Such a scrape is not successful, but it has a separate error code.
+var ErrNoData = errors.New("no data")
+
+// Code returns error code for given error: AWS error code, or generic code for other errors.
+func Code(err error) string {
+	var awsErr awserr.Error
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	switch {
+	case errors.Is(err, ErrNoData):
+		return "NoData"
+	case errors.As(err, &awsErr):
+		return awsErr.Code()
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Timeout"
+	case errors.Is(err, context.Canceled):
+		return "Canceled"
+	case errors.As(err, &syntaxErr), errors.As(err, &typeErr):
+		return "ParseError"
+	default:
+		return "Unknown"
+	}
+}
+
+// Error counts a scrape error for given instance and metric name (empty if error is not specific to a metric).
+func (t *Tracker) Error(region, instance, metric string, err error) {
+	if t == nil {
+		return
+	}
+
+	t.mErrors.WithLabelValues(t.source, region, instance, metric, Code(err)).Inc()
+}
+
+// Done sets the last scrape result for given instance.
+func (t *Tracker) Done(region, instance string, success bool) {
+	if t == nil {
+		return
+	}
+
+	if !success {
+		t.mSuccess.WithLabelValues(t.source, region, instance).Set(0)
+		return
+	}
+	t.mSuccess.WithLabelValues(t.source, region, instance).Set(1)
+	t.mLastSuccess.WithLabelValues(t.source, region, instance).Set(float64(time.Now().UnixNano()) / 1e9)
+}
+
+// Describe implements prometheus.Collector.
+func (t *Tracker) Describe(ch chan<- *prometheus.Desc) {
+	if t == nil {
+		return
+	}
+
+	t.mSuccess.Describe(ch)
+	t.mErrors.Describe(ch)
+	t.mLastSuccess.Describe(ch)
+}
+
+// Collect implements prometheus.Collector.
+func (t *Tracker) Collect(ch chan<- prometheus.Metric) {
+	if t == nil {
+		return
+	}
+
+	t.mSuccess.Collect(ch)
+	t.mErrors.Collect(ch)
+	t.mLastSuccess.Collect(ch)
+}
+
+// check interfaces
+var (
+	_ prometheus.Collector = (*Tracker)(nil)
+)
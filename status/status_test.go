@@ -0,0 +1,48 @@
+package status
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws/awserr"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCode(t *testing.T) {
+	var syntaxErr error = json.Unmarshal([]byte("{"), new(interface{}))
+
+	assert.Equal(t, "AccessDenied", Code(awserr.New("AccessDenied", "denied", nil)))
+	assert.Equal(t, "Timeout", Code(fmt.Errorf("wrapped: %w", context.DeadlineExceeded)))
+	assert.Equal(t, "ParseError", Code(syntaxErr))
+	assert.Equal(t, "NoData", Code(ErrNoData))
+	assert.Equal(t, "Unknown", Code(errors.New("some error")))
+}
+
+func TestTracker(t *testing.T) {
+	var nilTracker *Tracker
+	nilTracker.Error("us-east-1", "rds-aurora1", "", errors.New("ignored"))
+	nilTracker.Done("us-east-1", "rds-aurora1", false)
+
+	tracker := New("basic")
+	tracker.Error("us-east-1", "rds-aurora1", "CPUUtilization", awserr.New("InternalError", "error", nil))
+	tracker.Done("us-east-1", "rds-aurora1", false)
+	tracker.Done("us-east-1", "rds-mysql57", true)
+
+	expected := `
+# HELP rds_exporter_scrape_errors_total Total number of errors during scrapes of instance's metrics by metric (empty for all metrics) and error code.
+# TYPE rds_exporter_scrape_errors_total counter
+rds_exporter_scrape_errors_total{code="InternalError",instance="rds-aurora1",metric="CPUUtilization",region="us-east-1",source="basic"} 1
+# HELP rds_exporter_scrape_success Whether the last scrape of instance's metrics was successful (1) or not (0).
+# TYPE rds_exporter_scrape_success gauge
+rds_exporter_scrape_success{instance="rds-aurora1",region="us-east-1",source="basic"} 0
+rds_exporter_scrape_success{instance="rds-mysql57",region="us-east-1",source="basic"} 1
+`
+	assert.NoError(t, testutil.CollectAndCompare(tracker, strings.NewReader(expected),
+		"rds_exporter_scrape_errors_total", "rds_exporter_scrape_success"))
+	assert.Equal(t, 1, testutil.CollectAndCount(tracker, "rds_exporter_last_scrape_success_timestamp_seconds"))
+}